@@ -4,8 +4,15 @@ import (
 	"log"
 	"opinion-monitor/internal/api"
 	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/digest"
+	"opinion-monitor/internal/events"
+	"opinion-monitor/internal/fingerprint"
+	"opinion-monitor/internal/ingest"
 	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/notifier"
+	"opinion-monitor/internal/webhook"
 	"opinion-monitor/internal/worker"
+	"opinion-monitor/pkg/ai"
 	"opinion-monitor/pkg/whisper"
 
 	"github.com/gin-contrib/cors"
@@ -30,34 +37,114 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Initialize job queue
-	jobQueue := worker.NewJobQueue()
+	// Tenants are assigned from the config on every start
+	for _, tenant := range cfg.Tenants {
+		if !ai.ValidTenantName(tenant.Name) {
+			log.Fatalf("Invalid tenant name %q", tenant.Name)
+		}
+	}
+	if err := models.AssignTenants(db, cfg.Tenants); err != nil {
+		log.Fatalf("Failed to assign tenants: %v", err)
+	}
 
-	// Initialize Whisper client
-	whisperClient := whisper.NewClient(cfg.Whisper.ServiceURL)
-	log.Printf("Whisper service configured at: %s", cfg.Whisper.ServiceURL)
+	// Initialize job queue (backed by the jobs table)
+	jobQueue := worker.NewJobQueue(db, cfg)
 
-	// Check Whisper service health (non-blocking)
-	if err := whisperClient.HealthCheck(); err != nil {
-		log.Printf("Warning: Whisper service health check failed: %v", err)
-		log.Printf("Audio transcription will be unavailable")
+	// Initialize AI client
+	aiClient, err := newAIClient(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize AI client: %v", err)
+	}
+	modelVision, modelChat := aiClient.Models()
+	log.Printf("AI provider %s configured (vision: %s, chat: %s)", cfg.AI.Provider, modelVision, modelChat)
+
+	// Prompt templates are read from disk on every job so they can be edited live
+	promptStore := ai.NewPromptStore(cfg.Prompts.Dir, cfg.Prompts.DefaultVersion)
+	if !promptStore.HasVersion(promptStore.DefaultVersion()) {
+		log.Fatalf("Prompt version %s not found in %s", promptStore.DefaultVersion(), cfg.Prompts.Dir)
+	}
+
+	// Initialize speech-to-text backend
+	transcriber, err := whisper.NewTranscriber(whisper.Config{
+		Provider:   cfg.Whisper.Provider,
+		ServiceURL: cfg.Whisper.ServiceURL,
+		BaseURL:    cfg.Whisper.BaseURL,
+		APIKey:     cfg.Whisper.APIKey,
+		Model:      cfg.Whisper.Model,
+		Language:   cfg.Whisper.Language,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize transcriber: %v", err)
+	}
+	if cfg.Whisper.Provider == whisper.ProviderOpenAI {
+		log.Printf("Transcription provider %s configured at: %s", cfg.Whisper.Provider, cfg.Whisper.BaseURL)
 	} else {
-		log.Printf("Whisper service is healthy")
+		log.Printf("Transcription provider %s configured at: %s", cfg.Whisper.Provider, cfg.Whisper.ServiceURL)
 	}
 
+	// Check Whisper service health (non-blocking)
+	if checker, ok := transcriber.(whisper.HealthChecker); ok {
+		if err := checker.HealthCheck(); err != nil {
+			log.Printf("Warning: Whisper service health check failed: %v", err)
+			log.Printf("Audio transcription will be unavailable")
+		} else {
+			log.Printf("Whisper service is healthy")
+		}
+	}
+
+	// Job progress is published here and streamed to clients
+	eventBus := events.NewBus()
+
+	// Webhook deliveries are queued with each report and sent in the background
+	webhookDispatcher := webhook.NewDispatcher(db, cfg.Webhooks)
+	webhookDispatcher.Start()
+
+	// Alert notifications go to the in-app inbox, email and chat webhooks
+	notifications := notifier.NewNotifier(db, cfg.Notify)
+	notifications.Start()
+
+	// Daily and weekly digests are made in the background and sent like alerts
+	digestScheduler := digest.NewScheduler(db, cfg.Digests, aiClient, promptStore, notifications, webhookDispatcher)
+	digestScheduler.Start()
+
 	// Start worker pool
-	workerPool := worker.NewWorkerPool(cfg, db, jobQueue, whisperClient)
+	workerPool := worker.NewWorkerPool(cfg, db, jobQueue, aiClient, promptStore, transcriber, eventBus, webhookDispatcher, notifications)
 	workerPool.Start()
 
+	// Uploaded files become videos and jobs here; resumable uploads are staged until complete
+	ingester := ingest.NewIngester(db, cfg.Server.UploadPath, jobQueue, cfg.Dedup)
+	uploads := ingest.NewUploads(db, ingester, cfg.Uploads)
+	uploads.Start()
+
+	// Videos can also be imported by URL or dropped into a watched folder
+	importer := ingest.NewImporter(ingester, cfg.Imports)
+	if cfg.Imports.WatchDir != "" {
+		var watchUser models.User
+		if err := db.Where("username = ?", cfg.Imports.WatchUser).First(&watchUser).Error; err != nil {
+			log.Printf("Warning: drop folder disabled, user %q not found", cfg.Imports.WatchUser)
+		} else if err := ingest.NewDropFolder(ingester, cfg.Imports, watchUser.ID).Start(); err != nil {
+			log.Printf("Warning: drop folder disabled: %v", err)
+		}
+	}
+
+	// Near-duplicates are found by the frame fingerprints the pipeline saves
+	similarityIndex := fingerprint.NewIndex(db, cfg.Fingerprints)
+	if n, err := similarityIndex.Reindex(); err != nil {
+		log.Printf("Warning: failed to reindex fingerprints: %v", err)
+	} else if n > 0 {
+		log.Printf("Reindexed the fingerprints of %d videos", n)
+	}
+
 	// Setup Gin router
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.LoggerWithFormatter(api.LogFormatter), gin.Recovery())
 
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://165.154.98.129:3000", "http://127.0.0.1:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With", "Upload-Offset", "Upload-Checksum"},
+		ExposeHeaders:    []string{"Content-Length", "Authorization", "Location", "Upload-Offset", "Upload-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600, // 12 hours
 	}))
@@ -67,9 +154,17 @@ func main() {
 
 	// Initialize handlers
 	authHandler := api.NewAuthHandler(db, cfg)
-	videoHandler := api.NewVideoHandler(db, cfg, jobQueue)
+	videoHandler := api.NewVideoHandler(db, cfg, jobQueue, promptStore, ingester, importer, similarityIndex)
+	uploadHandler := api.NewUploadHandler(db, uploads)
 	reportHandler := api.NewReportHandler(db)
-	jobHandler := api.NewJobHandler(db)
+	jobHandler := api.NewJobHandler(db, jobQueue)
+	eventHandler := api.NewEventHandler(eventBus)
+	webhookHandler := api.NewWebhookHandler(db, webhookDispatcher)
+	watchlistHandler := api.NewWatchlistHandler(db)
+	alertHandler := api.NewAlertHandler(db)
+	ruleHandler := api.NewRuleHandler(db)
+	notificationHandler := api.NewNotificationHandler(db, notifications)
+	digestHandler := api.NewDigestHandler(db, digestScheduler)
 
 	// Auth routes
 	authGroup := r.Group("/api/auth")
@@ -83,22 +178,93 @@ func main() {
 	}
 
 	// Protected routes
+	// Real-time job events (Server-Sent Events); the only route taking the
+	// token in the query
+	r.GET("/api/events", api.EventStreamAuthMiddleware(cfg), eventHandler.Stream)
+
 	apiGroup := r.Group("/api")
 	apiGroup.Use(api.AuthMiddleware(cfg))
 	{
 		// Video routes
 		apiGroup.POST("/videos/upload", videoHandler.Upload)
+		apiGroup.POST("/videos/import", videoHandler.Import)
+		apiGroup.POST("/videos/reanalyze", videoHandler.BulkReanalyze)
+		apiGroup.POST("/videos/:id/reanalyze", videoHandler.Reanalyze)
 		apiGroup.GET("/videos", videoHandler.List)
 		apiGroup.GET("/videos/:id", videoHandler.Get)
+		apiGroup.GET("/videos/:id/similar", videoHandler.Similar)
 		apiGroup.DELETE("/videos/:id", videoHandler.Delete)
 
+		// Resumable upload routes
+		apiGroup.POST("/uploads", uploadHandler.Create)
+		apiGroup.GET("/uploads/:id", uploadHandler.Get)
+		apiGroup.HEAD("/uploads/:id", uploadHandler.Head)
+		apiGroup.PATCH("/uploads/:id", uploadHandler.Patch)
+		apiGroup.POST("/uploads/:id/complete", uploadHandler.Complete)
+		apiGroup.DELETE("/uploads/:id", uploadHandler.Delete)
+
 		// Report routes
 		apiGroup.GET("/reports/:video_id", reportHandler.GetByVideoID)
+		apiGroup.GET("/reports/:video_id/versions", reportHandler.Versions)
+		apiGroup.PUT("/reports/:video_id/current", reportHandler.SetCurrent)
 		apiGroup.GET("/reports", reportHandler.List)
 
 		// Job routes
 		apiGroup.GET("/jobs/:id/status", jobHandler.GetStatus)
+		apiGroup.GET("/jobs/:id/attempts", jobHandler.Attempts)
+		apiGroup.POST("/jobs/:id/requeue", jobHandler.Requeue)
 		apiGroup.GET("/jobs", jobHandler.List)
+
+		// Webhook routes
+		apiGroup.POST("/webhooks", webhookHandler.Create)
+		apiGroup.GET("/webhooks", webhookHandler.List)
+		apiGroup.PUT("/webhooks/:id", webhookHandler.Update)
+		apiGroup.DELETE("/webhooks/:id", webhookHandler.Delete)
+		apiGroup.GET("/webhooks/:id/deliveries", webhookHandler.Deliveries)
+		apiGroup.POST("/webhooks/:id/test", webhookHandler.Test)
+
+		// Watchlist routes
+		apiGroup.POST("/watchlists", watchlistHandler.Create)
+		apiGroup.GET("/watchlists", watchlistHandler.List)
+		apiGroup.GET("/watchlists/:id", watchlistHandler.Get)
+		apiGroup.PUT("/watchlists/:id", watchlistHandler.Update)
+		apiGroup.DELETE("/watchlists/:id", watchlistHandler.Delete)
+		apiGroup.POST("/watchlists/:id/terms", watchlistHandler.AddTerm)
+		apiGroup.DELETE("/watchlists/:id/terms/:term_id", watchlistHandler.DeleteTerm)
+
+		// Alert rule routes
+		apiGroup.POST("/rules", ruleHandler.Create)
+		apiGroup.GET("/rules", ruleHandler.List)
+		apiGroup.POST("/rules/dry-run", ruleHandler.DryRun)
+		apiGroup.GET("/rules/:id", ruleHandler.Get)
+		apiGroup.PUT("/rules/:id", ruleHandler.Update)
+		apiGroup.DELETE("/rules/:id", ruleHandler.Delete)
+		apiGroup.POST("/rules/:id/dry-run", ruleHandler.DryRun)
+
+		// Alert routes
+		apiGroup.GET("/alerts", alertHandler.List)
+		apiGroup.POST("/alerts/:id/acknowledge", alertHandler.Acknowledge)
+		apiGroup.POST("/alerts/:id/resolve", alertHandler.Resolve)
+
+		// Notification routes: in-app inbox, delivery log and channels
+		apiGroup.GET("/notifications", notificationHandler.List)
+		apiGroup.POST("/notifications/:id/read", notificationHandler.MarkRead)
+		apiGroup.POST("/notifications/read-all", notificationHandler.MarkAllRead)
+		apiGroup.GET("/notifications/deliveries", notificationHandler.Deliveries)
+		apiGroup.POST("/notification-channels", notificationHandler.CreateChannel)
+		apiGroup.GET("/notification-channels", notificationHandler.ListChannels)
+		apiGroup.PUT("/notification-channels/:id", notificationHandler.UpdateChannel)
+		apiGroup.DELETE("/notification-channels/:id", notificationHandler.DeleteChannel)
+		apiGroup.POST("/notification-channels/:id/test", notificationHandler.TestChannel)
+
+		// Digest routes
+		apiGroup.POST("/digest-schedules", digestHandler.CreateSchedule)
+		apiGroup.GET("/digest-schedules", digestHandler.ListSchedules)
+		apiGroup.PUT("/digest-schedules/:id", digestHandler.UpdateSchedule)
+		apiGroup.DELETE("/digest-schedules/:id", digestHandler.DeleteSchedule)
+		apiGroup.POST("/digest-schedules/:id/run", digestHandler.RunSchedule)
+		apiGroup.GET("/digests", digestHandler.List)
+		apiGroup.GET("/digests/:id", digestHandler.Get)
 	}
 
 	// Start server
@@ -107,3 +273,26 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// newAIClient creates the model backend selected by ai.provider.
+func newAIClient(cfg *config.Config) (ai.Client, error) {
+	aiCfg := ai.Config{
+		Provider:    cfg.AI.Provider,
+		BaseURL:     cfg.OpenAI.APIBase,
+		APIKey:      cfg.OpenAI.APIKey,
+		ModelVision: cfg.OpenAI.ModelVision,
+		ModelChat:   cfg.OpenAI.ModelChat,
+
+		StructuredOutput: cfg.OpenAI.StructuredOutput,
+		MaxInputTokens:   cfg.AI.MaxInputTokens,
+	}
+
+	if cfg.AI.Provider == ai.ProviderOllama {
+		aiCfg.BaseURL = cfg.Ollama.BaseURL
+		aiCfg.APIKey = ""
+		aiCfg.ModelVision = cfg.Ollama.ModelVision
+		aiCfg.ModelChat = cfg.Ollama.ModelChat
+	}
+
+	return ai.NewClient(aiCfg)
+}
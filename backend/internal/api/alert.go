@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"opinion-monitor/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AlertHandler struct {
+	db *gorm.DB
+}
+
+func NewAlertHandler(db *gorm.DB) *AlertHandler {
+	return &AlertHandler{db: db}
+}
+
+// List returns the alerts raised on the user's videos and by the user's
+// watchlists, newest first.
+func (h *AlertHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := h.visible(userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if videoID := c.Query("video_id"); videoID != "" {
+		query = query.Where("video_id = ?", videoID)
+	}
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if watchlistID := c.Query("watchlist_id"); watchlistID != "" {
+		query = query.Where("watchlist_id = ?", watchlistID)
+	}
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	if location := c.Query("location"); location != "" {
+		query = query.Where("location = ?", location)
+	}
+
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+
+	var alerts []models.Alert
+	if err := query.Preload("Watchlist").Preload("Rule").Preload("Video").
+		Order("created_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":    alerts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// Acknowledge marks an open alert as seen.
+func (h *AlertHandler) Acknowledge(c *gin.Context) {
+	h.transition(c, []models.AlertStatus{models.AlertStatusOpen}, models.AlertStatusAcknowledged, "acknowledged")
+}
+
+// Resolve closes an open or acknowledged alert.
+func (h *AlertHandler) Resolve(c *gin.Context) {
+	h.transition(c, []models.AlertStatus{models.AlertStatusOpen, models.AlertStatusAcknowledged}, models.AlertStatusResolved, "resolved")
+}
+
+// transition moves the alert named by the :id param from one of the from
+// statuses to the to status, recording who did it and when.
+func (h *AlertHandler) transition(c *gin.Context, from []models.AlertStatus, to models.AlertStatus, column string) {
+	userID, _ := c.Get("user_id")
+
+	var alert models.Alert
+	if err := h.visible(userID).Where("id = ?", c.Param("id")).First(&alert).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alert"})
+		return
+	}
+
+	result := h.db.Model(&models.Alert{}).
+		Where("id = ? AND status IN ?", alert.ID, from).
+		Updates(map[string]interface{}{
+			"status":       to,
+			column + "_by": userID,
+			column + "_at": time.Now(),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Alert is already " + string(alert.Status)})
+		return
+	}
+
+	h.db.First(&alert, alert.ID)
+	c.JSON(http.StatusOK, alert)
+}
+
+// visible scopes a query to the alerts userID can see: those on their own
+// videos and those raised by watchlists they own.
+func (h *AlertHandler) visible(userID interface{}) *gorm.DB {
+	return h.db.Model(&models.Alert{}).Where("user_id = ? OR watchlist_id IN (?)", userID,
+		h.db.Model(&models.Watchlist{}).Select("id").Where("user_id = ?", userID))
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"opinion-monitor/internal/config"
 	"opinion-monitor/internal/models"
 	"opinion-monitor/pkg/auth"
@@ -76,6 +78,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Username:     req.Username,
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
+		Tenant:       h.cfg.TenantOf(req.Username),
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
@@ -228,30 +231,68 @@ func (h *AuthHandler) Me(c *gin.Context) {
 }
 
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, cfg, c.GetHeader("Authorization"))
+	}
+}
+
+// EventStreamAuthMiddleware is AuthMiddleware for the event stream, which
+// also takes the token as ?access_token= because EventSource cannot set
+// headers. No other route accepts it; tokens in URLs end up in logs.
+func EventStreamAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
+		if authHeader == "" && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
 		}
+		authenticate(c, cfg, authHeader)
+	}
+}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
-			return
-		}
+func authenticate(c *gin.Context, cfg *config.Config, authHeader string) {
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return
+	}
 
-		claims, err := auth.ValidateAccessToken(parts[1], cfg.JWT.Secret)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return
+	}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Next()
+	claims, err := auth.ValidateAccessToken(parts[1], cfg.JWT.Secret)
+	if err != nil || claims.UserID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
 	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Next()
+}
+
+// LogFormatter is gin's access log line without colors and with the value
+// of access_token query parameters replaced, so stream tokens are not
+// written to the logs.
+func LogFormatter(param gin.LogFormatterParams) string {
+	path := param.Path
+	if u, err := url.Parse(path); err == nil && u.Query().Has("access_token") {
+		query := u.Query()
+		query.Set("access_token", "REDACTED")
+		u.RawQuery = query.Encode()
+		path = u.String()
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		path,
+		param.ErrorMessage,
+	)
 }
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"net/http"
+	"opinion-monitor/internal/digest"
+	"opinion-monitor/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DigestHandler struct {
+	db        *gorm.DB
+	scheduler *digest.Scheduler
+}
+
+func NewDigestHandler(db *gorm.DB, scheduler *digest.Scheduler) *DigestHandler {
+	return &DigestHandler{db: db, scheduler: scheduler}
+}
+
+type CreateDigestScheduleRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Period   string   `json:"period" binding:"required"` // daily or weekly
+	Scope    string   `json:"scope"`                     // user (default) or tenant
+	Channels []string `json:"channels"`                  // see models.AlertChannels
+	Summary  bool     `json:"summary"`                   // ask the chat model for an executive summary
+}
+
+type UpdateDigestScheduleRequest struct {
+	Name     *string  `json:"name"`
+	Period   *string  `json:"period"`
+	Scope    *string  `json:"scope"`
+	Channels []string `json:"channels"`
+	Summary  *bool    `json:"summary"`
+	Active   *bool    `json:"active"`
+}
+
+func (h *DigestHandler) CreateSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateDigestScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateDigestPeriod(req.Period); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	channels, msg := normalizeAlertChannels(req.Channels)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = models.DigestScopeUser
+	}
+	tenant, ok := h.scopeTenant(c, userID, scope)
+	if !ok {
+		return
+	}
+
+	next := h.scheduler.NextRun(req.Period, time.Now())
+	schedule := models.DigestSchedule{
+		UserID:    userID.(uint),
+		Name:      strings.TrimSpace(req.Name),
+		Period:    req.Period,
+		Scope:     scope,
+		Tenant:    tenant,
+		Channels:  channels,
+		Summary:   req.Summary,
+		Active:    true,
+		NextRunAt: &next,
+	}
+	if err := h.db.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create digest schedule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *DigestHandler) ListSchedules(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var schedules []models.DigestSchedule
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// UpdateSchedule changes a schedule. Changing the period or reactivating it
+// moves the next run to the next send time from now.
+func (h *DigestHandler) UpdateSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	schedule, ok := h.findUserSchedule(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateDigestScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	reschedule := false
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Period != nil {
+		if msg := validateDigestPeriod(*req.Period); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["period"] = *req.Period
+		schedule.Period = *req.Period
+		reschedule = true
+	}
+	if req.Scope != nil {
+		tenant, ok := h.scopeTenant(c, userID, *req.Scope)
+		if !ok {
+			return
+		}
+		updates["scope"] = *req.Scope
+		updates["tenant"] = tenant
+	}
+	if req.Channels != nil {
+		channels, msg := normalizeAlertChannels(req.Channels)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["channels"] = channels
+	}
+	if req.Summary != nil {
+		updates["summary"] = *req.Summary
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+		if *req.Active && !schedule.Active {
+			reschedule = true
+		}
+	}
+	if reschedule {
+		updates["next_run_at"] = h.scheduler.NextRun(schedule.Period, time.Now())
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(schedule).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update digest schedule"})
+			return
+		}
+	}
+
+	h.db.First(schedule, schedule.ID)
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *DigestHandler) DeleteSchedule(c *gin.Context) {
+	schedule, ok := h.findUserSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete digest schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Digest schedule deleted successfully"})
+}
+
+// RunSchedule makes and sends the schedule's digest right away, for the
+// period ending now. The schedule's next run is not changed.
+func (h *DigestHandler) RunSchedule(c *gin.Context) {
+	schedule, ok := h.findUserSchedule(c)
+	if !ok {
+		return
+	}
+
+	end := time.Now()
+	result, err := h.scheduler.Generate(schedule, digest.PeriodStart(schedule.Period, end), end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate digest"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// List returns the user's digests, newest first.
+func (h *DigestHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := h.db.Model(&models.Digest{}).Where("user_id = ?", userID)
+	if scheduleID := c.Query("schedule_id"); scheduleID != "" {
+		query = query.Where("schedule_id = ?", scheduleID)
+	}
+	if period := c.Query("period"); period != "" {
+		query = query.Where("period = ?", period)
+	}
+
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+
+	var digests []models.Digest
+	if err := query.Order("id DESC").Limit(pageSize).Offset(offset).Find(&digests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"digests":   digests,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (h *DigestHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var result models.Digest
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&result).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Digest not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// scopeTenant validates a scope and returns the tenant it covers. It writes
+// the error response itself when it returns false.
+func (h *DigestHandler) scopeTenant(c *gin.Context, userID interface{}, scope string) (string, bool) {
+	switch scope {
+	case models.DigestScopeUser:
+		return "", true
+	case models.DigestScopeTenant:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scope must be user or tenant"})
+		return "", false
+	}
+
+	var user models.User
+	if err := h.db.Select("id", "tenant").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return "", false
+	}
+	if user.Tenant == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only users in a tenant can make tenant digests"})
+		return "", false
+	}
+	return user.Tenant, true
+}
+
+// findUserSchedule loads the schedule named by the :id param if it belongs to
+// the current user. It writes the error response itself when it returns false.
+func (h *DigestHandler) findUserSchedule(c *gin.Context) (*models.DigestSchedule, bool) {
+	userID, _ := c.Get("user_id")
+
+	var schedule models.DigestSchedule
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&schedule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Digest schedule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digest schedule"})
+		return nil, false
+	}
+
+	return &schedule, true
+}
+
+func validateDigestPeriod(period string) string {
+	switch period {
+	case models.DigestPeriodDaily, models.DigestPeriodWeekly:
+		return ""
+	}
+	return "Period must be daily or weekly"
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"opinion-monitor/internal/events"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle streams open through proxies.
+const heartbeatInterval = 15 * time.Second
+
+type EventHandler struct {
+	bus *events.Bus
+}
+
+func NewEventHandler(bus *events.Bus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// Stream pushes the current user's job events as Server-Sent Events.
+// ?job_id= limits the stream to one job. Events are not replayed: a client
+// that reconnects misses what happened meanwhile and should reload the job.
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var jobID uint64
+	if id := c.Query("job_id"); id != "" {
+		var err error
+		jobID, err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+			return
+		}
+	}
+
+	ch, unsubscribe := h.bus.Subscribe(userID)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx buffering
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if jobID == 0 || uint64(e.JobID) == jobID {
+				c.SSEvent(string(e.Type), e)
+			}
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		}
+	})
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/worker"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -10,30 +12,23 @@ import (
 )
 
 type JobHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	jobQueue *worker.JobQueue
 }
 
-func NewJobHandler(db *gorm.DB) *JobHandler {
-	return &JobHandler{db: db}
+func NewJobHandler(db *gorm.DB, jobQueue *worker.JobQueue) *JobHandler {
+	return &JobHandler{db: db, jobQueue: jobQueue}
 }
 
 func (h *JobHandler) GetStatus(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	jobID := c.Param("id")
-
-	// Check if job belongs to user
-	var job models.Job
-	if err := h.db.Preload("Video").Where("id = ?", jobID).First(&job).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job"})
+	job, ok := h.findUserJob(c)
+	if !ok {
 		return
 	}
 
-	if job.Video.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	// Include pipeline stages so clients can see where the job is
+	if err := h.db.Where("job_id = ?", job.ID).Order("position ASC").Find(&job.Stages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job stages"})
 		return
 	}
 
@@ -45,6 +40,7 @@ func (h *JobHandler) List(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status := c.Query("status")
 
 	if page < 1 {
 		page = 1
@@ -58,14 +54,18 @@ func (h *JobHandler) List(c *gin.Context) {
 	var jobs []models.Job
 	var total int64
 
-	h.db.Model(&models.Job{}).
+	query := h.db.Model(&models.Job{}).
 		Joins("JOIN videos ON jobs.video_id = videos.id").
-		Where("videos.user_id = ?", userID).
-		Count(&total)
+		Where("videos.user_id = ?", userID)
 
-	if err := h.db.Preload("Video").
-		Joins("JOIN videos ON jobs.video_id = videos.id").
-		Where("videos.user_id = ?", userID).
+	// status=dead lists the dead-letter queue
+	if status != "" {
+		query = query.Where("jobs.status = ?", status)
+	}
+
+	query.Session(&gorm.Session{}).Count(&total)
+
+	if err := query.Preload("Video").
 		Order("jobs.created_at DESC").
 		Limit(pageSize).
 		Offset(offset).
@@ -81,3 +81,64 @@ func (h *JobHandler) List(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+func (h *JobHandler) Attempts(c *gin.Context) {
+	job, ok := h.findUserJob(c)
+	if !ok {
+		return
+	}
+
+	var attempts []models.JobAttempt
+	if err := h.db.Where("job_id = ?", job.ID).Order("id ASC").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":   job.ID,
+		"attempts": attempts,
+	})
+}
+
+// Requeue moves a dead-lettered or failed job back into the queue.
+func (h *JobHandler) Requeue(c *gin.Context) {
+	job, ok := h.findUserJob(c)
+	if !ok {
+		return
+	}
+
+	if err := h.jobQueue.Requeue(job.ID); err != nil {
+		if errors.Is(err, worker.ErrNotRequeueable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only dead or failed jobs can be requeued"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job requeued successfully"})
+}
+
+// findUserJob loads the job named by the :id param and checks that it belongs
+// to the current user. It writes the error response itself when it returns false.
+func (h *JobHandler) findUserJob(c *gin.Context) (*models.Job, bool) {
+	userID, _ := c.Get("user_id")
+	jobID := c.Param("id")
+
+	var job models.Job
+	if err := h.db.Preload("Video").Where("id = ?", jobID).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job"})
+		return nil, false
+	}
+
+	if job.Video.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return &job, true
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"net/http"
+	"net/mail"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/notifier"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	db       *gorm.DB
+	notifier *notifier.Notifier
+}
+
+func NewNotificationHandler(db *gorm.DB, n *notifier.Notifier) *NotificationHandler {
+	return &NotificationHandler{db: db, notifier: n}
+}
+
+type CreateChannelRequest struct {
+	Type   string `json:"type" binding:"required"` // email, feishu, dingtalk, wecom or slack
+	Name   string `json:"name"`
+	Target string `json:"target" binding:"required"` // email address or webhook URL
+	Secret string `json:"secret"`                    // Feishu and DingTalk signing secret
+}
+
+type UpdateChannelRequest struct {
+	Name   *string `json:"name"`
+	Target *string `json:"target"`
+	Secret *string `json:"secret"`
+	Active *bool   `json:"active"`
+}
+
+// List returns the user's inbox, newest first. ?unread=true limits it to
+// unread notifications.
+func (h *NotificationHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := h.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total, unread int64
+	query.Session(&gorm.Session{}).Count(&total)
+	h.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Limit(pageSize).Offset(offset).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"unread":        unread,
+		"page":          page,
+		"page_size":     pageSize,
+	})
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := h.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", c.Param("id"), userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	var notification models.Notification
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := h.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// Deliveries returns the log of email and chat messages sent to the user,
+// newest first.
+func (h *NotificationHandler) Deliveries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := h.db.Model(&models.NotificationDelivery{}).Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if channelID := c.Query("channel_id"); channelID != "" {
+		query = query.Where("channel_id = ?", channelID)
+	}
+
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+
+	var deliveries []models.NotificationDelivery
+	if err := query.Order("id DESC").Limit(pageSize).Offset(offset).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target := strings.TrimSpace(req.Target)
+	if msg := validateChannelTarget(req.Type, target, h.notifier.CheckURL); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	channel := models.NotificationChannel{
+		UserID: userID.(uint),
+		Type:   req.Type,
+		Name:   strings.TrimSpace(req.Name),
+		Target: target,
+		Secret: req.Secret,
+		Active: true,
+	}
+	if err := h.db.Create(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create channel"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+func (h *NotificationHandler) ListChannels(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var channels []models.NotificationChannel
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels})
+}
+
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	channel, ok := h.findUserChannel(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Target != nil {
+		target := strings.TrimSpace(*req.Target)
+		if msg := validateChannelTarget(channel.Type, target, h.notifier.CheckURL); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["target"] = target
+	}
+	if req.Secret != nil {
+		updates["secret"] = *req.Secret
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(channel).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update channel"})
+			return
+		}
+	}
+
+	h.db.First(channel, channel.ID)
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	channel, ok := h.findUserChannel(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel deleted successfully"})
+}
+
+// TestChannel sends a test message to the channel right away and returns the result.
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	channel, ok := h.findUserChannel(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.notifier.Test(channel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// findUserChannel loads the channel named by the :id param if it belongs to
+// the current user. It writes the error response itself when it returns false.
+func (h *NotificationHandler) findUserChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	userID, _ := c.Get("user_id")
+
+	var channel models.NotificationChannel
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&channel).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return nil, false
+	}
+
+	return &channel, true
+}
+
+func validateChannelTarget(channelType, target string, checkAddress func(string) error) string {
+	if channelType == models.ChannelTypeEmail {
+		if _, err := mail.ParseAddress(target); err != nil {
+			return "Target must be an email address"
+		}
+		return ""
+	}
+
+	for _, t := range models.ChatChannelTypes {
+		if channelType == t {
+			return validateWebhookURL(target, checkAddress)
+		}
+	}
+	return "Unknown channel type: " + channelType + " (expected email, " + strings.Join(models.ChatChannelTypes, ", ") + ")"
+}
@@ -32,8 +32,23 @@ func (h *ReportHandler) GetByVideoID(c *gin.Context) {
 		return
 	}
 
+	// Return the current version unless a specific one is requested
+	query := h.db.Where("video_id = ?", videoID)
+	if version := c.Query("version"); version != "" {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Where("is_current = ?", true)
+	}
+
 	var report models.Report
-	if err := h.db.Where("video_id = ?", videoID).Preload("Video").First(&report).Error; err != nil {
+	if err := query.Preload("Video").
+		Preload("Video.TranscriptSegments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		Preload("Frames", func(db *gorm.DB) *gorm.DB {
+			return db.Order("timestamp ASC")
+		}).
+		First(&report).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 			return
@@ -66,12 +81,12 @@ func (h *ReportHandler) List(c *gin.Context) {
 
 	h.db.Model(&models.Report{}).
 		Joins("JOIN videos ON reports.video_id = videos.id").
-		Where("videos.user_id = ?", userID).
+		Where("videos.user_id = ? AND reports.is_current = ?", userID, true).
 		Count(&total)
 
 	if err := h.db.Preload("Video").
 		Joins("JOIN videos ON reports.video_id = videos.id").
-		Where("videos.user_id = ?", userID).
+		Where("videos.user_id = ? AND reports.is_current = ?", userID, true).
 		Order("reports.created_at DESC").
 		Limit(pageSize).
 		Offset(offset).
@@ -87,3 +102,81 @@ func (h *ReportHandler) List(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// Versions lists every report version of a video, newest first.
+func (h *ReportHandler) Versions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	videoID := c.Param("video_id")
+
+	var video models.Video
+	if err := h.db.Where("id = ? AND user_id = ?", videoID, userID).First(&video).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return
+	}
+
+	var reports []models.Report
+	if err := h.db.Where("video_id = ?", video.ID).Order("version DESC").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id": video.ID,
+		"versions": reports,
+	})
+}
+
+type SetCurrentReportRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// SetCurrent makes the given report version the one shown for the video.
+func (h *ReportHandler) SetCurrent(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	videoID := c.Param("video_id")
+
+	var req SetCurrentReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var video models.Video
+	if err := h.db.Where("id = ? AND user_id = ?", videoID, userID).First(&video).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return
+	}
+
+	var report models.Report
+	if err := h.db.Where("video_id = ? AND version = ?", video.ID, req.Version).First(&report).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report version not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Report{}).
+			Where("video_id = ? AND id <> ?", video.ID, report.ID).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&report).Update("is_current", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
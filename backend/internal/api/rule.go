@@ -0,0 +1,356 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/rules"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDryRunLimit = 200
+	maxDryRunLimit     = 2000
+)
+
+type RuleHandler struct {
+	db *gorm.DB
+}
+
+func NewRuleHandler(db *gorm.DB) *RuleHandler {
+	return &RuleHandler{db: db}
+}
+
+type CreateRuleRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description"`
+	Condition   json.RawMessage `json:"condition" binding:"required"`
+	Severity    string          `json:"severity"` // low, medium (default) or high
+	Channels    []string        `json:"channels"`
+}
+
+type UpdateRuleRequest struct {
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Condition   json.RawMessage `json:"condition"`
+	Severity    *string         `json:"severity"`
+	Channels    []string        `json:"channels"`
+	Active      *bool           `json:"active"`
+}
+
+type DryRunRequest struct {
+	Condition json.RawMessage `json:"condition"` // required unless a saved rule is dry-run
+	Since     *time.Time      `json:"since"`
+	Until     *time.Time      `json:"until"`
+	Limit     int             `json:"limit"`
+}
+
+// DryRunMatch is a historical report that satisfies the rule.
+type DryRunMatch struct {
+	ReportID         uint      `json:"report_id"`
+	VideoID          uint      `json:"video_id"`
+	OriginalFilename string    `json:"original_filename"`
+	Version          int       `json:"version"`
+	RiskLevel        string    `json:"risk_level"`
+	SentimentScore   float64   `json:"sentiment_score"`
+	Reasons          []string  `json:"reasons"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (h *RuleHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	condition, msg := normalizeRuleCondition(req.Condition)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	severity := req.Severity
+	if severity == "" {
+		severity = "medium"
+	}
+	if msg := validateSeverity(severity); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	channels, msg := normalizeAlertChannels(req.Channels)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	rule := models.AlertRule{
+		UserID:      userID.(uint),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Condition:   condition,
+		Severity:    severity,
+		Channels:    channels,
+		Active:      true,
+	}
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *RuleHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var alertRules []models.AlertRule
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&alertRules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules":  alertRules,
+		"fields": rules.FieldNames(),
+	})
+}
+
+func (h *RuleHandler) Get(c *gin.Context) {
+	rule, ok := h.findUserRule(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RuleHandler) Update(c *gin.Context) {
+	rule, ok := h.findUserRule(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Condition != nil {
+		condition, msg := normalizeRuleCondition(req.Condition)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["condition"] = condition
+	}
+	if req.Severity != nil {
+		if msg := validateSeverity(*req.Severity); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["severity"] = *req.Severity
+	}
+	if req.Channels != nil {
+		channels, msg := normalizeAlertChannels(req.Channels)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["channels"] = channels
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(rule).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+			return
+		}
+	}
+
+	h.db.First(rule, rule.ID)
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RuleHandler) Delete(c *gin.Context) {
+	rule, ok := h.findUserRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
+
+// DryRun evaluates a condition against the user's current reports, newest
+// first, without raising alerts. With an :id param the saved rule's
+// condition is used.
+func (h *RuleHandler) DryRun(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req DryRunRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	raw := req.Condition
+	if c.Param("id") != "" {
+		rule, ok := h.findUserRule(c)
+		if !ok {
+			return
+		}
+		raw = json.RawMessage(rule.Condition)
+	}
+	if raw == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Condition is required"})
+		return
+	}
+
+	condition, err := rules.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := req.Limit
+	if limit < 1 {
+		limit = defaultDryRunLimit
+	}
+	if limit > maxDryRunLimit {
+		limit = maxDryRunLimit
+	}
+
+	query := h.db.Joins("JOIN videos ON videos.id = reports.video_id").
+		Where("videos.user_id = ? AND videos.deleted_at IS NULL AND reports.is_current = ?", userID, true)
+	if req.Since != nil {
+		query = query.Where("reports.created_at >= ?", *req.Since)
+	}
+	if req.Until != nil {
+		query = query.Where("reports.created_at < ?", *req.Until)
+	}
+
+	var reports []models.Report
+	if err := query.Preload("Video").
+		Order("reports.created_at DESC").
+		Limit(limit).
+		Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	matches := []DryRunMatch{}
+	for i := range reports {
+		report := &reports[i]
+		ok, reasons := condition.Evaluate(rules.FactsFromReport(report))
+		if !ok {
+			continue
+		}
+		matches = append(matches, DryRunMatch{
+			ReportID:         report.ID,
+			VideoID:          report.VideoID,
+			OriginalFilename: report.Video.OriginalFilename,
+			Version:          report.Version,
+			RiskLevel:        report.RiskLevel,
+			SentimentScore:   report.SentimentScore,
+			Reasons:          reasons,
+			CreatedAt:        report.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"condition": condition.String(),
+		"evaluated": len(reports),
+		"matched":   len(matches),
+		"matches":   matches,
+	})
+}
+
+// findUserRule loads the rule named by the :id param if it belongs to the
+// current user. It writes the error response itself when it returns false.
+func (h *RuleHandler) findUserRule(c *gin.Context) (*models.AlertRule, bool) {
+	userID, _ := c.Get("user_id")
+
+	var rule models.AlertRule
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rule"})
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// normalizeRuleCondition validates a condition and re-encodes it compactly
+// for storage.
+func normalizeRuleCondition(raw json.RawMessage) (string, string) {
+	condition, err := rules.Parse(raw)
+	if err != nil {
+		return "", err.Error()
+	}
+	encoded, err := json.Marshal(condition)
+	if err != nil {
+		return "", "Invalid condition"
+	}
+	return string(encoded), ""
+}
+
+func validateSeverity(severity string) string {
+	switch severity {
+	case "low", "medium", "high":
+		return ""
+	}
+	return "Severity must be low, medium or high"
+}
+
+// normalizeAlertChannels checks the channel names and joins them for storage.
+func normalizeAlertChannels(channels []string) (string, string) {
+	seen := make(map[string]bool)
+	var valid []string
+	for _, ch := range channels {
+		ch = strings.TrimSpace(ch)
+		known := false
+		for _, k := range models.AlertChannels {
+			if ch == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", "Unknown channel: " + ch + " (expected one of " + strings.Join(models.AlertChannels, ", ") + ")"
+		}
+		if !seen[ch] {
+			seen[ch] = true
+			valid = append(valid, ch)
+		}
+	}
+	return strings.Join(valid, ","), ""
+}
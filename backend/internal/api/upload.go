@@ -0,0 +1,258 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"opinion-monitor/internal/ingest"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/pkg/video"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Headers of the resumable upload protocol, named after the tus protocol.
+const (
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadLength   = "Upload-Length"
+	headerUploadChecksum = "Upload-Checksum" // "sha256 <base64 digest>" of the chunk
+
+	uploadChunkContentType = "application/offset+octet-stream"
+
+	// statusChecksumMismatch is the status tus uses for a chunk whose
+	// checksum does not match.
+	statusChecksumMismatch = 460
+)
+
+type UploadHandler struct {
+	db      *gorm.DB
+	uploads *ingest.Uploads
+}
+
+func NewUploadHandler(db *gorm.DB, uploads *ingest.Uploads) *UploadHandler {
+	return &UploadHandler{db: db, uploads: uploads}
+}
+
+type CreateUploadRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required,min=1"`
+	Checksum string `json:"checksum"` // SHA-256 of the whole file, hex; verified by Complete
+}
+
+// Create starts a resumable upload. The response's Location header is where
+// the chunks are sent.
+func (h *UploadHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := filepath.Base(strings.TrimSpace(req.Filename))
+	if !video.IsVideoFile(filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported file type"})
+		return
+	}
+	if max := h.uploads.MaxSize(); max > 0 && req.Size > max {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is larger than the %d byte limit", max)})
+		return
+	}
+	checksum := strings.ToLower(strings.TrimSpace(req.Checksum))
+	if checksum != "" {
+		if sum, err := hex.DecodeString(checksum); err != nil || len(sum) != sha256.Size {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Checksum must be a hex SHA-256 digest"})
+			return
+		}
+	}
+
+	upload, err := h.uploads.Create(userID.(uint), filename, req.Size, checksum)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/uploads/%d", upload.ID))
+	setUploadHeaders(c, upload)
+	c.JSON(http.StatusCreated, upload)
+}
+
+func (h *UploadHandler) Get(c *gin.Context) {
+	upload, ok := h.findUserUpload(c)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.JSON(http.StatusOK, upload)
+}
+
+// Head returns the upload's offset, where the client resumes from, in the
+// Upload-Offset header.
+func (h *UploadHandler) Head(c *gin.Context) {
+	upload, ok := h.findUserUpload(c)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Status(http.StatusOK)
+}
+
+// Patch appends the request body at the offset given in Upload-Offset.
+func (h *UploadHandler) Patch(c *gin.Context) {
+	upload, ok := h.findUserUpload(c)
+	if !ok {
+		return
+	}
+
+	if c.ContentType() != uploadChunkContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + uploadChunkContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + headerUploadOffset + " header"})
+		return
+	}
+	var chunkSum []byte
+	if header := c.GetHeader(headerUploadChecksum); header != "" {
+		if chunkSum, err = parseChunkChecksum(header); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	newOffset, err := h.uploads.Append(upload, offset, c.Request.Body, chunkSum)
+	c.Header(headerUploadOffset, strconv.FormatInt(newOffset, 10))
+	if err != nil {
+		switch {
+		case errors.Is(err, ingest.ErrOffsetMismatch):
+			c.JSON(http.StatusConflict, gin.H{"error": "Offset does not match the upload offset", "offset": newOffset})
+		case errors.Is(err, ingest.ErrUploadClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Upload is already completed"})
+		case errors.Is(err, ingest.ErrUploadBusy):
+			c.JSON(http.StatusLocked, gin.H{"error": "Upload is being written by another request"})
+		case errors.Is(err, ingest.ErrChunkTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk exceeds the remaining upload length or the maximum chunk size"})
+		case errors.Is(err, ingest.ErrChecksumMismatch):
+			c.JSON(statusChecksumMismatch, gin.H{"error": "Chunk checksum mismatch"})
+		case errors.Is(err, ingest.ErrUploadInterrupted):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk was interrupted", "offset": newOffset})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write chunk"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Complete finishes an upload whose every byte has been received and queues
+// the video for analysis.
+func (h *UploadHandler) Complete(c *gin.Context) {
+	upload, ok := h.findUserUpload(c)
+	if !ok {
+		return
+	}
+
+	videoRecord, err := h.uploads.Complete(upload)
+	if err != nil {
+		var duplicate *ingest.DuplicateError
+		switch {
+		case errors.As(err, &duplicate):
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "Video was already uploaded",
+				"duplicate": true,
+				"video":     duplicate.Video,
+			})
+		case errors.Is(err, ingest.ErrUploadIncomplete):
+			c.JSON(http.StatusConflict, gin.H{"error": "Upload is incomplete", "offset": upload.Offset, "size": upload.Size})
+		case errors.Is(err, ingest.ErrUploadClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Upload is already completed", "video_id": upload.VideoID})
+		case errors.Is(err, ingest.ErrUploadBusy):
+			c.JSON(http.StatusLocked, gin.H{"error": "Upload is being written by another request"})
+		case errors.Is(err, ingest.ErrChecksumMismatch):
+			c.JSON(statusChecksumMismatch, gin.H{"error": "File checksum mismatch; the upload was discarded"})
+		case errors.Is(err, ingest.ErrNotVideo), errors.Is(err, video.ErrInvalidVideo):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File is not a readable video; the upload was discarded"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete upload"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Video uploaded successfully",
+		"duplicate": videoRecord.DuplicateOfID != nil,
+		"video":     videoRecord,
+	})
+}
+
+// Delete aborts an unfinished upload and discards its data.
+func (h *UploadHandler) Delete(c *gin.Context) {
+	upload, ok := h.findUserUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Status != models.UploadStatusUploading {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is already completed"})
+		return
+	}
+
+	if err := h.uploads.Abort(upload); err != nil {
+		if errors.Is(err, ingest.ErrUploadBusy) {
+			c.JSON(http.StatusLocked, gin.H{"error": "Upload is being written by another request"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload deleted successfully"})
+}
+
+// findUserUpload loads the upload named by the :id param if it belongs to
+// the current user. It writes the error response itself when it returns false.
+func (h *UploadHandler) findUserUpload(c *gin.Context) (*models.Upload, bool) {
+	userID, _ := c.Get("user_id")
+
+	var upload models.Upload
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upload"})
+		return nil, false
+	}
+
+	return &upload, true
+}
+
+func setUploadHeaders(c *gin.Context, upload *models.Upload) {
+	c.Header(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Header(headerUploadLength, strconv.FormatInt(upload.Size, 10))
+	c.Header("Cache-Control", "no-store")
+}
+
+// parseChunkChecksum decodes an Upload-Checksum header, "sha256 <base64>".
+func parseChunkChecksum(header string) ([]byte, error) {
+	algorithm, encoded, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(algorithm, "sha256") {
+		return nil, errors.New("Upload-Checksum must be \"sha256 <base64 digest>\"")
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, errors.New("Upload-Checksum must be \"sha256 <base64 digest>\"")
+	}
+	return sum, nil
+}
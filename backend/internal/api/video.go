@@ -1,16 +1,22 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
 	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/fingerprint"
+	"opinion-monitor/internal/ingest"
 	"opinion-monitor/internal/models"
 	"opinion-monitor/internal/worker"
+	"opinion-monitor/pkg/ai"
 	"opinion-monitor/pkg/video"
 	"os"
 	"path/filepath"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,16 +26,48 @@ type VideoHandler struct {
 	db       *gorm.DB
 	cfg      *config.Config
 	jobQueue *worker.JobQueue
+	prompts  *ai.PromptStore
+	ingester *ingest.Ingester
+	importer *ingest.Importer
+	index    *fingerprint.Index
 }
 
-func NewVideoHandler(db *gorm.DB, cfg *config.Config, jobQueue *worker.JobQueue) *VideoHandler {
+func NewVideoHandler(db *gorm.DB, cfg *config.Config, jobQueue *worker.JobQueue, prompts *ai.PromptStore, ingester *ingest.Ingester, importer *ingest.Importer, index *fingerprint.Index) *VideoHandler {
 	return &VideoHandler{
 		db:       db,
 		cfg:      cfg,
 		jobQueue: jobQueue,
+		prompts:  prompts,
+		ingester: ingester,
+		importer: importer,
+		index:    index,
 	}
 }
 
+// Reasons a file of a batch upload is rejected.
+const (
+	UploadRejectedType       = "unsupported_type"
+	UploadRejectedSize       = "too_large"
+	UploadRejectedContent    = "invalid_content"
+	UploadRejectedUnreadable = "unreadable_video"
+	UploadRejectedStorage    = "storage_failed"
+	UploadRejectedInternal   = "internal_error"
+)
+
+// UploadResult is the outcome of one file of a batch upload. A duplicate is
+// accepted either as the user's existing video or as a new video reusing the
+// analysis of the original.
+type UploadResult struct {
+	Filename  string        `json:"filename"`
+	Accepted  bool          `json:"accepted"`
+	Duplicate bool          `json:"duplicate,omitempty"`
+	Video     *models.Video `json:"video,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Upload stores a batch of videos and queues them for analysis. Each file is
+// checked by extension, size and content; the response has a result per file.
 func (h *VideoHandler) Upload(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -45,80 +83,86 @@ func (h *VideoHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	// Create upload directory
-	userDir := filepath.Join(h.cfg.Server.UploadPath, fmt.Sprintf("%d", userID), time.Now().Format("2006-01-02"))
-	if err := os.MkdirAll(userDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
-		return
-	}
-
+	results := make([]UploadResult, 0, len(files))
 	uploadedVideos := []models.Video{}
 
 	for _, file := range files {
-		// Validate file type
-		if !video.IsVideoFile(file.Filename) {
-			continue
-		}
-
-		// Check file size
-		if file.Size > h.cfg.Server.MaxFileSize {
-			continue
-		}
-
-		// Generate unique filename
-		filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(file.Filename))
-		filePath := filepath.Join(userDir, filename)
-
-		// Save file
-		if err := c.SaveUploadedFile(file, filePath); err != nil {
-			continue
-		}
-
-		// Get video duration
-		processor := video.NewProcessor()
-		duration, _ := processor.GetVideoDuration(filePath)
-
-		// Create video record
-		videoRecord := models.Video{
-			UserID:           userID.(uint),
-			OriginalFilename: file.Filename,
-			FilePath:         filePath,
-			FileSize:         file.Size,
-			Duration:         duration,
-			Status:           models.StatusPending,
+		result := h.uploadFile(c, userID.(uint), file)
+		if result.Accepted {
+			uploadedVideos = append(uploadedVideos, *result.Video)
 		}
+		results = append(results, result)
+	}
 
-		if err := h.db.Create(&videoRecord).Error; err != nil {
-			os.Remove(filePath)
-			continue
-		}
+	if len(uploadedVideos) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No valid videos uploaded",
+			"results": results,
+		})
+		return
+	}
 
-		// Create job
-		job := models.Job{
-			VideoID: videoRecord.ID,
-			Status:  models.JobStatusPending,
-		}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Videos uploaded successfully",
+		"count":    len(uploadedVideos),
+		"rejected": len(results) - len(uploadedVideos),
+		"videos":   uploadedVideos,
+		"results":  results,
+	})
+}
 
-		if err := h.db.Create(&job).Error; err != nil {
-			continue
-		}
+// uploadFile stores and ingests one file of a batch upload. A rejected file
+// is reported with one of the UploadRejected codes and a message for the client.
+func (h *VideoHandler) uploadFile(c *gin.Context, userID uint, file *multipart.FileHeader) UploadResult {
+	result := UploadResult{Filename: file.Filename}
+	reject := func(code, message string) UploadResult {
+		result.Code = code
+		result.Error = message
+		return result
+	}
 
-		// Queue job
-		h.jobQueue.Push(videoRecord.ID)
+	if !video.IsVideoFile(file.Filename) {
+		return reject(UploadRejectedType, "unsupported file type")
+	}
+	if file.Size > h.cfg.Server.MaxFileSize {
+		return reject(UploadRejectedSize, fmt.Sprintf("file is larger than the %d byte limit", h.cfg.Server.MaxFileSize))
+	}
 
-		uploadedVideos = append(uploadedVideos, videoRecord)
+	filePath, err := h.ingester.StoragePath(userID, file.Filename)
+	if err != nil {
+		return reject(UploadRejectedStorage, "failed to save file")
+	}
+	if err := c.SaveUploadedFile(file, filePath); err != nil {
+		os.Remove(filePath)
+		return reject(UploadRejectedStorage, "failed to save file")
 	}
 
-	if len(uploadedVideos) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid videos uploaded"})
-		return
+	videoRecord, err := h.ingester.Ingest(userID, file.Filename, filePath, file.Size)
+	if err != nil {
+		os.Remove(filePath)
+
+		var duplicate *ingest.DuplicateError
+		switch {
+		case errors.As(err, &duplicate):
+			result.Accepted = true
+			result.Duplicate = true
+			result.Video = duplicate.Video
+			return result
+		case errors.Is(err, ingest.ErrNotVideo):
+			return reject(UploadRejectedContent, "content is not a supported video container")
+		case errors.Is(err, video.ErrInvalidVideo):
+			// The ffprobe output names the file on the server; keep it in the log
+			log.Printf("Rejected upload %q of user %d: %v", file.Filename, userID, err)
+			return reject(UploadRejectedUnreadable, video.ErrInvalidVideo.Error())
+		default:
+			return reject(UploadRejectedInternal, "failed to create video")
+		}
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Videos uploaded successfully",
-		"count":   len(uploadedVideos),
-		"videos":  uploadedVideos,
-	})
+	result.Accepted = true
+	result.Duplicate = videoRecord.DuplicateOfID != nil
+	result.Video = videoRecord
+	return result
 }
 
 func (h *VideoHandler) List(c *gin.Context) {
@@ -166,8 +210,11 @@ func (h *VideoHandler) Get(c *gin.Context) {
 
 	var videoRecord models.Video
 	if err := h.db.Where("id = ? AND user_id = ?", videoID, userID).
-		Preload("Report").
+		Preload("Report", "is_current = ?", true).
 		Preload("Job").
+		Preload("TranscriptSegments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
 		First(&videoRecord).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
@@ -180,6 +227,52 @@ func (h *VideoHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, videoRecord)
 }
 
+// Similar lists near-duplicates of a video found by perceptual fingerprint:
+// re-uploads and re-encoded, cropped or watermarked copies, most similar first.
+func (h *VideoHandler) Similar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	videoID := c.Param("id")
+
+	minSimilarity := h.index.MinSimilarity()
+	if v := c.Query("min_similarity"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_similarity must be between 0 and 1"})
+			return
+		}
+		minSimilarity = parsed
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var videoRecord models.Video
+	if err := h.db.Where("id = ? AND user_id = ?", videoID, userID).First(&videoRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return
+	}
+
+	matches, err := h.index.Similar(&videoRecord, minSimilarity, limit)
+	if err != nil {
+		if errors.Is(err, fingerprint.ErrNoFingerprint) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Video has not been fingerprinted yet"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find similar videos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id": videoRecord.ID,
+		"similar":  matches,
+	})
+}
+
 func (h *VideoHandler) Delete(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	videoID := c.Param("id")
@@ -199,6 +292,7 @@ func (h *VideoHandler) Delete(c *gin.Context) {
 	if videoRecord.CoverPath != "" {
 		os.Remove(videoRecord.CoverPath)
 	}
+	os.RemoveAll(filepath.Join(filepath.Dir(videoRecord.FilePath), fmt.Sprintf("frames_%d", videoRecord.ID)))
 
 	// Delete from database (soft delete)
 	if err := h.db.Delete(&videoRecord).Error; err != nil {
@@ -208,3 +302,230 @@ func (h *VideoHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Video deleted successfully"})
 }
+
+type ReanalyzeRequest struct {
+	ModelVision   string `json:"model_vision"`
+	ModelChat     string `json:"model_chat"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+type BulkReanalyzeRequest struct {
+	VideoIDs      []uint `json:"video_ids" binding:"required,min=1,max=100"`
+	ModelVision   string `json:"model_vision"`
+	ModelChat     string `json:"model_chat"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+type ReanalyzeResult struct {
+	VideoID uint   `json:"video_id"`
+	Queued  bool   `json:"queued"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Reanalyze queues an existing video for another analysis run, optionally
+// with different models or prompts. The result is saved as a new report version.
+func (h *VideoHandler) Reanalyze(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	videoID := c.Param("id")
+
+	var req ReanalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PromptVersion != "" && !h.prompts.HasVersion(req.PromptVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown prompt version"})
+		return
+	}
+
+	var videoRecord models.Video
+	if err := h.db.Where("id = ? AND user_id = ?", videoID, userID).First(&videoRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return
+	}
+
+	if err := h.jobQueue.Reanalyze(videoRecord.ID, worker.AnalysisOverrides{
+		ModelVision:   req.ModelVision,
+		ModelChat:     req.ModelChat,
+		PromptVersion: req.PromptVersion,
+	}); err != nil {
+		status, message := reanalyzeError(err)
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Video queued for reanalysis"})
+}
+
+// BulkReanalyze queues several videos for reanalysis and reports the outcome per video.
+func (h *VideoHandler) BulkReanalyze(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req BulkReanalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PromptVersion != "" && !h.prompts.HasVersion(req.PromptVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown prompt version"})
+		return
+	}
+
+	// Only the user's own videos can be reanalyzed
+	var ownedIDs []uint
+	if err := h.db.Model(&models.Video{}).
+		Where("id IN ? AND user_id = ?", req.VideoIDs, userID).
+		Pluck("id", &ownedIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch videos"})
+		return
+	}
+
+	owned := make(map[uint]bool, len(ownedIDs))
+	for _, id := range ownedIDs {
+		owned[id] = true
+	}
+
+	overrides := worker.AnalysisOverrides{
+		ModelVision:   req.ModelVision,
+		ModelChat:     req.ModelChat,
+		PromptVersion: req.PromptVersion,
+	}
+
+	results := make([]ReanalyzeResult, 0, len(req.VideoIDs))
+	queued := 0
+	for _, videoID := range req.VideoIDs {
+		if !owned[videoID] {
+			results = append(results, ReanalyzeResult{VideoID: videoID, Error: "Video not found"})
+			continue
+		}
+
+		if err := h.jobQueue.Reanalyze(videoID, overrides); err != nil {
+			_, message := reanalyzeError(err)
+			results = append(results, ReanalyzeResult{VideoID: videoID, Error: message})
+			continue
+		}
+
+		queued++
+		results = append(results, ReanalyzeResult{VideoID: videoID, Queued: true})
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"queued":  queued,
+		"results": results,
+	})
+}
+
+type ImportRequest struct {
+	URLs []string `json:"urls" binding:"required,min=1,max=20"`
+}
+
+// Outcomes of one URL of an import.
+const (
+	ImportStatusImported  = "imported"
+	ImportStatusDuplicate = "duplicate" // an existing video was returned
+	ImportStatusFailed    = "failed"
+)
+
+type ImportResult struct {
+	URL       string        `json:"url"`
+	Status    string        `json:"status"`
+	Duplicate bool          `json:"duplicate,omitempty"`
+	Video     *models.Video `json:"video,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Import downloads videos from URLs and queues them for analysis, reporting
+// the outcome per URL. URLs are fetched one after another within the request,
+// so the response is final: 200 when every URL gave a video, 207 when some
+// failed and 400 when none did. Downloads stop when the client goes away or
+// the request runs out of time; the URLs not reached count as failed.
+func (h *VideoHandler) Import(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := h.importer.RequestContext(c.Request.Context())
+	defer cancel()
+
+	results := make([]ImportResult, 0, len(req.URLs))
+	imported, failed := 0, 0
+	for _, rawURL := range req.URLs {
+		if ctx.Err() != nil {
+			failed++
+			results = append(results, ImportResult{URL: rawURL, Status: ImportStatusFailed, Error: "Import request ran out of time"})
+			continue
+		}
+
+		videoRecord, err := h.importer.Import(ctx, userID.(uint), rawURL)
+		var duplicate *ingest.DuplicateError
+		switch {
+		case errors.As(err, &duplicate):
+			results = append(results, ImportResult{URL: rawURL, Status: ImportStatusDuplicate, Duplicate: true, Video: duplicate.Video})
+			continue
+		case err != nil:
+			failed++
+			results = append(results, ImportResult{URL: rawURL, Status: ImportStatusFailed, Error: importError(err)})
+			continue
+		}
+
+		imported++
+		results = append(results, ImportResult{URL: rawURL, Status: ImportStatusImported, Duplicate: videoRecord.DuplicateOfID != nil, Video: videoRecord})
+	}
+
+	status := http.StatusOK
+	switch {
+	case failed == len(req.URLs):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No videos imported",
+			"results": results,
+		})
+		return
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+
+	c.JSON(status, gin.H{
+		"imported": imported,
+		"failed":   failed,
+		"results":  results,
+	})
+}
+
+// importError maps an Importer.Import error to a message for the client.
+func importError(err error) string {
+	switch {
+	case errors.Is(err, ingest.ErrUnsupportedURL),
+		errors.Is(err, ingest.ErrPrivateAddress),
+		errors.Is(err, ingest.ErrDownloadFailed),
+		errors.Is(err, ingest.ErrFileTooLarge),
+		errors.Is(err, ingest.ErrNotVideo):
+		return err.Error()
+	case errors.Is(err, video.ErrInvalidVideo):
+		log.Printf("Rejected import: %v", err)
+		return video.ErrInvalidVideo.Error()
+	default:
+		return "Failed to import video"
+	}
+}
+
+// reanalyzeError maps a JobQueue.Reanalyze error to an HTTP status and message.
+func reanalyzeError(err error) (int, string) {
+	switch {
+	case errors.Is(err, worker.ErrJobActive):
+		return http.StatusConflict, "Video is already being analyzed"
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "Job not found"
+	default:
+		return http.StatusInternalServerError, "Failed to queue reanalysis"
+	}
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"net/http"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/watchlist"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WatchlistHandler struct {
+	db *gorm.DB
+}
+
+func NewWatchlistHandler(db *gorm.DB) *WatchlistHandler {
+	return &WatchlistHandler{db: db}
+}
+
+type WatchlistTermRequest struct {
+	Term          string `json:"term" binding:"required"`
+	Category      string `json:"category"`
+	MatchType     string `json:"match_type"` // contains (default), word or regex
+	CaseSensitive bool   `json:"case_sensitive"`
+}
+
+type CreateWatchlistRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Shared      bool                   `json:"shared"`   // applies to every user in the owner's tenant
+	Channels    []string               `json:"channels"` // where alerts are sent, see models.AlertChannels
+	Terms       []WatchlistTermRequest `json:"terms" binding:"dive"`
+}
+
+type UpdateWatchlistRequest struct {
+	Name        *string                `json:"name"`
+	Description *string                `json:"description"`
+	Shared      *bool                  `json:"shared"`
+	Active      *bool                  `json:"active"`
+	Channels    []string               `json:"channels"`
+	Terms       []WatchlistTermRequest `json:"terms" binding:"dive"` // replaces all terms when set
+}
+
+func (h *WatchlistHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateWatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms, msg := buildWatchlistTerms(req.Terms)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	channels, msg := normalizeAlertChannels(req.Channels)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	list := models.Watchlist{
+		UserID:      userID.(uint),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Channels:    channels,
+		Active:      true,
+		Terms:       terms,
+	}
+	if req.Shared {
+		tenant, ok := h.sharingTenant(c, userID)
+		if !ok {
+			return
+		}
+		list.Shared = true
+		list.Tenant = tenant
+	}
+
+	if err := h.db.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// List returns the user's own watchlists and those shared within their tenant.
+func (h *WatchlistHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var lists []models.Watchlist
+	if err := h.visible(userID).Preload("Terms").Order("created_at DESC").Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watchlists": lists})
+}
+
+func (h *WatchlistHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var list models.Watchlist
+	if err := h.visible(userID).Preload("Terms").Where("id = ?", c.Param("id")).First(&list).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Update changes a watchlist. Only its owner can change it, including shared ones.
+func (h *WatchlistHandler) Update(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	list, ok := h.findOwnWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if req.Channels != nil {
+		channels, msg := normalizeAlertChannels(req.Channels)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["channels"] = channels
+	}
+	if req.Shared != nil {
+		updates["shared"] = *req.Shared
+		updates["tenant"] = ""
+		if *req.Shared {
+			tenant, ok := h.sharingTenant(c, userID)
+			if !ok {
+				return
+			}
+			updates["tenant"] = tenant
+		}
+	}
+
+	var terms []models.WatchlistTerm
+	if req.Terms != nil {
+		var msg string
+		if terms, msg = buildWatchlistTerms(req.Terms); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(list).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Terms == nil {
+			return nil
+		}
+		if err := tx.Where("watchlist_id = ?", list.ID).Delete(&models.WatchlistTerm{}).Error; err != nil {
+			return err
+		}
+		for i := range terms {
+			terms[i].WatchlistID = list.ID
+		}
+		if len(terms) > 0 {
+			return tx.Create(&terms).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update watchlist"})
+		return
+	}
+
+	h.db.Preload("Terms").First(list, list.ID)
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *WatchlistHandler) Delete(c *gin.Context) {
+	list, ok := h.findOwnWatchlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist deleted successfully"})
+}
+
+// AddTerm adds a single term to a watchlist.
+func (h *WatchlistHandler) AddTerm(c *gin.Context) {
+	list, ok := h.findOwnWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var req WatchlistTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms, msg := buildWatchlistTerms([]WatchlistTermRequest{req})
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	term := terms[0]
+	term.WatchlistID = list.ID
+	if err := h.db.Create(&term).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add term"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+func (h *WatchlistHandler) DeleteTerm(c *gin.Context) {
+	list, ok := h.findOwnWatchlist(c)
+	if !ok {
+		return
+	}
+
+	result := h.db.Where("id = ? AND watchlist_id = ?", c.Param("term_id"), list.ID).Delete(&models.WatchlistTerm{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete term"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Term deleted successfully"})
+}
+
+// visible scopes a query to the watchlists userID can see.
+func (h *WatchlistHandler) visible(userID interface{}) *gorm.DB {
+	var user models.User
+	h.db.Select("id", "tenant").First(&user, userID)
+
+	if user.Tenant == "" {
+		return h.db.Where("user_id = ?", userID)
+	}
+	return h.db.Where("user_id = ? OR (shared = ? AND tenant = ?)", userID, true, user.Tenant)
+}
+
+// sharingTenant returns the tenant a watchlist is shared with. It writes the
+// error response itself when it returns false.
+func (h *WatchlistHandler) sharingTenant(c *gin.Context, userID interface{}) (string, bool) {
+	var user models.User
+	if err := h.db.Select("id", "tenant").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return "", false
+	}
+	if user.Tenant == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only users in a tenant can share watchlists"})
+		return "", false
+	}
+	return user.Tenant, true
+}
+
+// findOwnWatchlist loads the watchlist named by the :id param if the current
+// user owns it. It writes the error response itself when it returns false.
+func (h *WatchlistHandler) findOwnWatchlist(c *gin.Context) (*models.Watchlist, bool) {
+	userID, _ := c.Get("user_id")
+
+	var list models.Watchlist
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&list).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist"})
+		return nil, false
+	}
+
+	return &list, true
+}
+
+// buildWatchlistTerms validates the requested terms and checks that they compile.
+func buildWatchlistTerms(reqs []WatchlistTermRequest) ([]models.WatchlistTerm, string) {
+	terms := make([]models.WatchlistTerm, 0, len(reqs))
+	for _, req := range reqs {
+		term := models.WatchlistTerm{
+			Term:          strings.TrimSpace(req.Term),
+			Category:      strings.TrimSpace(req.Category),
+			MatchType:     req.MatchType,
+			CaseSensitive: req.CaseSensitive,
+		}
+		if term.Term == "" {
+			return nil, "Term cannot be empty"
+		}
+		if term.MatchType == "" {
+			term.MatchType = models.MatchTypeContains
+		}
+		if _, err := watchlist.Compile(&term); err != nil {
+			return nil, "Invalid term: " + err.Error()
+		}
+		terms = append(terms, term)
+	}
+	return terms, ""
+}
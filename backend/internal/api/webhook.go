@@ -0,0 +1,274 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/webhook"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	db         *gorm.DB
+	dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookHandler(db *gorm.DB, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{db: db, dispatcher: dispatcher}
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret"` // generated when empty
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Secret *string  `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// Create registers a webhook. The signing secret is only returned here.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateWebhookURL(req.URL, h.dispatcher.CheckURL); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	events, msg := normalizeWebhookEvents(req.Events)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhook.GenerateSecret(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		UserID: userID.(uint),
+		URL:    req.URL,
+		Events: events,
+		Secret: secret,
+		Active: true,
+	}
+	if err := h.db.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": hook,
+		"secret":  secret,
+	})
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var hooks []models.Webhook
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	hook, ok := h.findUserWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if msg := validateWebhookURL(*req.URL, h.dispatcher.CheckURL); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		events, msg := normalizeWebhookEvents(req.Events)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["events"] = events
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Secret cannot be empty"})
+			return
+		}
+		updates["secret"] = *req.Secret
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(hook).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+			return
+		}
+	}
+
+	h.db.First(hook, hook.ID)
+	c.JSON(http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	hook, ok := h.findUserWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// Deliveries returns the delivery log of a webhook, newest first.
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	hook, ok := h.findUserWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Session(&gorm.Session{}).Count(&total)
+
+	if err := query.Order("id DESC").Limit(pageSize).Offset(offset).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// Test sends a ping event to the webhook right away and returns the result.
+func (h *WebhookHandler) Test(c *gin.Context) {
+	hook, ok := h.findUserWebhook(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Test(hook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test delivery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// findUserWebhook loads the webhook named by the :id param if it belongs to
+// the current user. It writes the error response itself when it returns false.
+func (h *WebhookHandler) findUserWebhook(c *gin.Context) (*models.Webhook, bool) {
+	userID, _ := c.Get("user_id")
+
+	var hook models.Webhook
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&hook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		return nil, false
+	}
+
+	return &hook, true
+}
+
+// validateWebhookURL checks that raw is an http(s) URL that checkAddress, if
+// given, accepts, and returns the error message otherwise.
+func validateWebhookURL(raw string, checkAddress func(string) error) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL must be an absolute http or https URL"
+	}
+	if checkAddress == nil {
+		return ""
+	}
+	if err := checkAddress(raw); err != nil {
+		return "URL must not point to a private or loopback address"
+	}
+	return ""
+}
+
+// normalizeWebhookEvents checks the event types and joins them for storage.
+func normalizeWebhookEvents(events []string) (string, string) {
+	seen := make(map[string]bool)
+	var valid []string
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		known := false
+		for _, k := range models.WebhookEvents {
+			if e == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", "Unknown event type: " + e + " (expected one of " + strings.Join(models.WebhookEvents, ", ") + ")"
+		}
+		if !seen[e] {
+			seen[e] = true
+			valid = append(valid, e)
+		}
+	}
+	return strings.Join(valid, ","), ""
+}
@@ -0,0 +1,30 @@
+// Package backoff computes the delay before retrying something that failed.
+// It is shared by the job retry policy and the outbox delivery loops.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Policy is an exponential backoff between Initial and Max.
+type Policy struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// Delay returns the delay before the next try after the attempt-th failure
+// (starting at 1). The delay doubles on every attempt, is capped at Max and
+// has up to 20% jitter so that things failing together do not retry together.
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.Initial
+	for i := 1; i < attempt && delay < p.Max; i++ {
+		delay *= 2
+	}
+	if delay > p.Max {
+		delay = p.Max
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
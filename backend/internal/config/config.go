@@ -5,12 +5,44 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	OpenAI   OpenAIConfig   `mapstructure:"openai"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Worker   WorkerConfig   `mapstructure:"worker"`
-	Whisper  WhisperConfig  `mapstructure:"whisper"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	AI           AIConfig           `mapstructure:"ai"`
+	OpenAI       OpenAIConfig       `mapstructure:"openai"`
+	Ollama       OllamaConfig       `mapstructure:"ollama"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Worker       WorkerConfig       `mapstructure:"worker"`
+	Whisper      WhisperConfig      `mapstructure:"whisper"`
+	Frames       FramesConfig       `mapstructure:"frames"`
+	Uploads      UploadsConfig      `mapstructure:"uploads"`
+	Imports      ImportsConfig      `mapstructure:"imports"`
+	Dedup        DedupConfig        `mapstructure:"dedup"`
+	Fingerprints FingerprintsConfig `mapstructure:"fingerprints"`
+	Webhooks     WebhooksConfig     `mapstructure:"webhooks"`
+	Notify       NotifyConfig       `mapstructure:"notifications"`
+	Digests      DigestsConfig      `mapstructure:"digests"`
+	Prompts      PromptsConfig      `mapstructure:"prompts"`
+	Tenants      []TenantConfig     `mapstructure:"tenants"`
+}
+
+// TenantConfig puts users in a tenant. Users of a tenant share watchlists,
+// analyses of duplicate videos, similarity search and tenant digests, and
+// get the tenant's prompt overrides.
+type TenantConfig struct {
+	Name  string   `mapstructure:"name"`  // also the prompts/<version>/tenants/<name> directory
+	Users []string `mapstructure:"users"` // usernames
+}
+
+// TenantOf returns the configured tenant of a user, or "".
+func (c *Config) TenantOf(username string) string {
+	for _, tenant := range c.Tenants {
+		for _, user := range tenant.Users {
+			if user == username {
+				return tenant.Name
+			}
+		}
+	}
+	return ""
 }
 
 type ServerConfig struct {
@@ -19,6 +51,43 @@ type ServerConfig struct {
 	MaxFileSize int64  `mapstructure:"max_file_size"`
 }
 
+// UploadsConfig configures resumable uploads.
+type UploadsConfig struct {
+	StagingDir   string `mapstructure:"staging_dir"`    // where partial uploads are kept until they are complete
+	MaxSize      int64  `mapstructure:"max_size"`       // largest file accepted; server.max_file_size only limits single-request uploads
+	Expiry       string `mapstructure:"expiry"`         // unfinished uploads are deleted after this long without new data
+	MaxChunkSize int64  `mapstructure:"max_chunk_size"` // bytes accepted per PATCH request
+}
+
+// ImportsConfig configures importing videos by URL and from a drop folder.
+type ImportsConfig struct {
+	MaxSize              int64  `mapstructure:"max_size"`               // largest file downloaded from a URL
+	Timeout              string `mapstructure:"timeout"`                // per URL, including the download
+	RequestTimeout       string `mapstructure:"request_timeout"`        // per import request, for all of its URLs
+	AllowPrivateNetworks bool   `mapstructure:"allow_private_networks"` // allow URLs on loopback and private addresses
+	WatchDir             string `mapstructure:"watch_dir"`              // files dropped here are ingested; empty disables
+	WatchUser            string `mapstructure:"watch_user"`             // username the dropped files are ingested for
+	WatchInterval        string `mapstructure:"watch_interval"`         // how often the drop folder is scanned
+}
+
+// DedupConfig configures what happens when a file with the same content as
+// an existing video is ingested.
+type DedupConfig struct {
+	Mode  string `mapstructure:"mode"`  // link, reuse or off
+	Scope string `mapstructure:"scope"` // user or tenant: whose videos are compared
+}
+
+// FingerprintsConfig configures the perceptual fingerprints used to find
+// near-duplicate videos. Frame hashes are indexed in MaxDistance+1 bands
+// (at least 4), so any two at most MaxDistance bits apart are found; larger
+// distances widen the search as the bands get narrower.
+type FingerprintsConfig struct {
+	Frames        int     `mapstructure:"frames"`         // frames hashed per video
+	MaxDistance   int     `mapstructure:"max_distance"`   // bits two frame hashes may differ in and still match
+	MinSimilarity float64 `mapstructure:"min_similarity"` // default threshold for similar videos, 0-1
+	Scope         string  `mapstructure:"scope"`          // user or tenant: whose videos are searched
+}
+
 type DatabaseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
@@ -32,21 +101,107 @@ type OpenAIConfig struct {
 	APIKey      string `mapstructure:"api_key"`
 	ModelVision string `mapstructure:"model_vision"`
 	ModelChat   string `mapstructure:"model_chat"`
+	// StructuredOutput requests JSON-schema constrained answers; disable for
+	// OpenAI-compatible servers that do not support response_format.
+	StructuredOutput bool `mapstructure:"structured_output"`
+}
+
+type AIConfig struct {
+	Provider       string `mapstructure:"provider"`         // openai, ollama or fake
+	MaxInputTokens int    `mapstructure:"max_input_tokens"` // longer content is analyzed in chunks and merged; 0 disables chunking
+}
+
+type OllamaConfig struct {
+	BaseURL     string `mapstructure:"base_url"`
+	ModelVision string `mapstructure:"model_vision"`
+	ModelChat   string `mapstructure:"model_chat"`
+}
+
+type PromptsConfig struct {
+	Dir            string `mapstructure:"dir"`             // <dir>/<version>/*.tmpl, see ai.PromptStore
+	DefaultVersion string `mapstructure:"default_version"` // version used unless a reanalysis asks for another
 }
 
 type JWTConfig struct {
-	Secret         string `mapstructure:"secret"`
-	Expiry         string `mapstructure:"expiry"`
-	RefreshSecret  string `mapstructure:"refresh_secret"`
-	RefreshExpiry  string `mapstructure:"refresh_expiry"`
+	Secret        string `mapstructure:"secret"`
+	Expiry        string `mapstructure:"expiry"`
+	RefreshSecret string `mapstructure:"refresh_secret"`
+	RefreshExpiry string `mapstructure:"refresh_expiry"`
 }
 
 type WorkerConfig struct {
-	Concurrency int `mapstructure:"concurrency"`
+	Concurrency   int         `mapstructure:"concurrency"`
+	LeaseDuration string      `mapstructure:"lease_duration"` // how long a claimed job stays invisible to other workers
+	PollInterval  string      `mapstructure:"poll_interval"`  // how often idle workers check the jobs table
+	Retry         RetryConfig `mapstructure:"retry"`
+}
+
+type RetryConfig struct {
+	MaxAttempts    int      `mapstructure:"max_attempts"`    // total runs before a job is dead-lettered
+	InitialBackoff string   `mapstructure:"initial_backoff"` // delay before the first retry, doubled on each attempt
+	MaxBackoff     string   `mapstructure:"max_backoff"`
+	FatalErrors    []string `mapstructure:"fatal_errors"` // error message substrings that are never retried
+}
+
+type FramesConfig struct {
+	Mode           string  `mapstructure:"mode"`            // uniform, scene or keyframes
+	Count          int     `mapstructure:"count"`           // frames for uniform sampling
+	SceneThreshold float64 `mapstructure:"scene_threshold"` // 0-1, lower detects more scene changes
+	MaxFrames      int     `mapstructure:"max_frames"`      // cap for scene and keyframe sampling
+}
+
+type WebhooksConfig struct {
+	MaxAttempts    int    `mapstructure:"max_attempts"`
+	InitialBackoff string `mapstructure:"initial_backoff"`
+	MaxBackoff     string `mapstructure:"max_backoff"`
+	Timeout        string `mapstructure:"timeout"` // per request
+	PollInterval   string `mapstructure:"poll_interval"`
+	// AllowPrivateNetworks allows webhook URLs on loopback and private addresses
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+type NotifyConfig struct {
+	BaseURL        string `mapstructure:"base_url"` // frontend address used for report links in messages
+	MaxAttempts    int    `mapstructure:"max_attempts"`
+	InitialBackoff string `mapstructure:"initial_backoff"`
+	MaxBackoff     string `mapstructure:"max_backoff"`
+	Timeout        string `mapstructure:"timeout"` // per message
+	PollInterval   string `mapstructure:"poll_interval"`
+	// AllowPrivateNetworks allows chat webhook URLs on loopback and private addresses
+	AllowPrivateNetworks bool       `mapstructure:"allow_private_networks"`
+	SMTP                 SMTPConfig `mapstructure:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"` // email is disabled when empty
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	TLS      string `mapstructure:"tls"` // starttls, tls (implicit, usually port 465) or none
+}
+
+type DigestsConfig struct {
+	SendAt       string `mapstructure:"send_at"`       // local time of day digests are made, HH:MM
+	Weekday      string `mapstructure:"weekday"`       // day weekly digests are made, e.g. monday
+	TopTopics    int    `mapstructure:"top_topics"`    // topics listed in a digest
+	TopVideos    int    `mapstructure:"top_videos"`    // highest-risk videos listed in a digest
+	PollInterval string `mapstructure:"poll_interval"` // how often due schedules are checked
 }
 
 type WhisperConfig struct {
-	ServiceURL string `mapstructure:"service_url"`
+	Provider   string `mapstructure:"provider"`    // service, openai, whispercpp or fake
+	ServiceURL string `mapstructure:"service_url"` // service and whispercpp
+	BaseURL    string `mapstructure:"base_url"`    // openai only; empty means https://api.openai.com/v1
+	APIKey     string `mapstructure:"api_key"`     // openai only
+	Model      string `mapstructure:"model"`       // openai only
+	Language   string `mapstructure:"language"`    // ISO 639-1 code; empty lets the backend detect it
+
+	// Audio longer than ChunkDuration is split at silences into overlapping
+	// chunks that are transcribed in parallel
+	ChunkDuration    string `mapstructure:"chunk_duration"`
+	ChunkOverlap     string `mapstructure:"chunk_overlap"`
+	ChunkConcurrency int    `mapstructure:"chunk_concurrency"`
 }
 
 func Load() (*Config, error) {
@@ -60,15 +215,42 @@ func Load() (*Config, error) {
 	viper.SetDefault("server.upload_path", "./uploads")
 	viper.SetDefault("server.max_file_size", 524288000) // 500MB
 
+	viper.SetDefault("uploads.staging_dir", "./staging")
+	viper.SetDefault("uploads.max_size", 5368709120) // 5GB
+	viper.SetDefault("uploads.expiry", "24h")
+	viper.SetDefault("uploads.max_chunk_size", 67108864) // 64MB
+
+	viper.SetDefault("imports.max_size", 2147483648) // 2GB
+	viper.SetDefault("imports.timeout", "10m")
+	viper.SetDefault("imports.request_timeout", "15m")
+	viper.SetDefault("imports.allow_private_networks", false)
+	viper.SetDefault("imports.watch_interval", "10s")
+
+	viper.SetDefault("dedup.mode", "link")
+	viper.SetDefault("dedup.scope", "user")
+
+	viper.SetDefault("fingerprints.frames", 32)
+	viper.SetDefault("fingerprints.max_distance", 12)
+	viper.SetDefault("fingerprints.min_similarity", 0.5)
+	viper.SetDefault("fingerprints.scope", "user")
+
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", "3306")
 	viper.SetDefault("database.name", "opinion_monitor")
 	viper.SetDefault("database.user", "root")
 	viper.SetDefault("database.password", "password")
 
+	viper.SetDefault("ai.provider", "openai")
+	viper.SetDefault("ai.max_input_tokens", 12000)
+
 	viper.SetDefault("openai.api_base", "https://api.openai.com/v1")
 	viper.SetDefault("openai.model_vision", "gpt-4o")
 	viper.SetDefault("openai.model_chat", "gpt-4o")
+	viper.SetDefault("openai.structured_output", true)
+
+	viper.SetDefault("ollama.base_url", "http://localhost:11434")
+	viper.SetDefault("ollama.model_vision", "qwen2.5vl")
+	viper.SetDefault("ollama.model_chat", "qwen2.5")
 
 	viper.SetDefault("jwt.secret", "your-secret-key-change-in-production")
 	viper.SetDefault("jwt.expiry", "15m")
@@ -76,8 +258,51 @@ func Load() (*Config, error) {
 	viper.SetDefault("jwt.refresh_expiry", "168h") // 7 days
 
 	viper.SetDefault("worker.concurrency", 5)
+	viper.SetDefault("worker.lease_duration", "10m")
+	viper.SetDefault("worker.poll_interval", "2s")
+	viper.SetDefault("worker.retry.max_attempts", 3)
+	viper.SetDefault("worker.retry.initial_backoff", "30s")
+	viper.SetDefault("worker.retry.max_backoff", "30m")
+	viper.SetDefault("worker.retry.fatal_errors", []string{})
 
+	viper.SetDefault("whisper.provider", "service")
 	viper.SetDefault("whisper.service_url", "http://localhost:5000")
+	viper.SetDefault("whisper.model", "whisper-1")
+	viper.SetDefault("whisper.chunk_duration", "10m")
+	viper.SetDefault("whisper.chunk_overlap", "2s")
+	viper.SetDefault("whisper.chunk_concurrency", 2)
+
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.initial_backoff", "30s")
+	viper.SetDefault("webhooks.max_backoff", "1h")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.poll_interval", "5s")
+	viper.SetDefault("webhooks.allow_private_networks", false)
+
+	viper.SetDefault("notifications.base_url", "http://localhost:3000")
+	viper.SetDefault("notifications.max_attempts", 5)
+	viper.SetDefault("notifications.initial_backoff", "1m")
+	viper.SetDefault("notifications.max_backoff", "1h")
+	viper.SetDefault("notifications.timeout", "10s")
+	viper.SetDefault("notifications.poll_interval", "5s")
+	viper.SetDefault("notifications.allow_private_networks", false)
+	viper.SetDefault("notifications.smtp.port", 587)
+	viper.SetDefault("notifications.smtp.from", "opinion-monitor@localhost")
+	viper.SetDefault("notifications.smtp.tls", "starttls")
+
+	viper.SetDefault("digests.send_at", "08:00")
+	viper.SetDefault("digests.weekday", "monday")
+	viper.SetDefault("digests.top_topics", 10)
+	viper.SetDefault("digests.top_videos", 5)
+	viper.SetDefault("digests.poll_interval", "1m")
+
+	viper.SetDefault("frames.mode", "uniform")
+	viper.SetDefault("frames.count", 6)
+	viper.SetDefault("frames.scene_threshold", 0.4)
+	viper.SetDefault("frames.max_frames", 12)
+
+	viper.SetDefault("prompts.dir", "./prompts")
+	viper.SetDefault("prompts.default_version", "v1")
 
 	// Allow environment variables
 	viper.AutomaticEnv()
@@ -0,0 +1,191 @@
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"opinion-monitor/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	sentimentLabels = []string{"positive", "neutral", "negative"}
+	riskLevels      = map[string]int{"low": 1, "medium": 2, "high": 3}
+)
+
+// Aggregate computes the figures of the current reports created in
+// [start, end) on the videos the schedule covers, with the trend against the
+// period of the same length before start.
+func Aggregate(db *gorm.DB, schedule *models.DigestSchedule, start, end time.Time, topTopics, topVideos int) (*models.DigestStats, error) {
+	current, err := loadReports(db, schedule, start, end)
+	if err != nil {
+		return nil, err
+	}
+	previousStart := start.Add(-end.Sub(start))
+	previous, err := loadReports(db, schedule, previousStart, start)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := tally(current)
+	prev := tally(previous)
+
+	stats := &models.DigestStats{
+		Total:        cur.total,
+		BySentiment:  cur.bySentiment,
+		ByRisk:       cur.byRisk,
+		AverageScore: cur.averageScore(),
+		TopTopics:    topTopicCounts(cur.topics, prev.topics, topTopics),
+		HighestRisk:  highestRisk(current, topVideos),
+		Trend: models.DigestTrend{
+			PreviousStart:        previousStart,
+			PreviousEnd:          start,
+			PreviousTotal:        prev.total,
+			TotalChange:          cur.total - prev.total,
+			PreviousAverageScore: prev.averageScore(),
+			PreviousNegative:     prev.bySentiment["negative"],
+			NegativeChange:       cur.bySentiment["negative"] - prev.bySentiment["negative"],
+			PreviousHighRisk:     prev.byRisk["high"],
+			HighRiskChange:       cur.byRisk["high"] - prev.byRisk["high"],
+		},
+	}
+	// Score changes are only meaningful when both periods have reports
+	if cur.total > 0 && prev.total > 0 {
+		stats.Trend.AverageScoreChange = stats.AverageScore - stats.Trend.PreviousAverageScore
+	}
+
+	return stats, nil
+}
+
+// loadReports returns the current report versions created in [start, end)
+// on the schedule's videos, with the video filename loaded.
+func loadReports(db *gorm.DB, schedule *models.DigestSchedule, start, end time.Time) ([]models.Report, error) {
+	query := db.Select("reports.id", "reports.video_id", "reports.sentiment_score", "reports.sentiment_label", "reports.risk_level", "reports.key_topics", "reports.created_at").
+		Joins("JOIN videos ON videos.id = reports.video_id").
+		Where("videos.deleted_at IS NULL AND reports.is_current = ?", true).
+		Where("reports.created_at >= ? AND reports.created_at < ?", start, end)
+
+	if schedule.Scope == models.DigestScopeTenant && schedule.Tenant != "" {
+		query = query.Where("videos.user_id IN (?)", db.Model(&models.User{}).Select("id").Where("tenant = ?", schedule.Tenant))
+	} else {
+		query = query.Where("videos.user_id = ?", schedule.UserID)
+	}
+
+	var reports []models.Report
+	err := query.Preload("Video", func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id", "original_filename")
+	}).Find(&reports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reports: %w", err)
+	}
+	return reports, nil
+}
+
+type counts struct {
+	total       int
+	scoreSum    float64
+	bySentiment map[string]int
+	byRisk      map[string]int
+	topics      map[string]int // reports naming each topic
+}
+
+func tally(reports []models.Report) *counts {
+	c := &counts{
+		bySentiment: make(map[string]int),
+		byRisk:      make(map[string]int),
+		topics:      make(map[string]int),
+	}
+	// Known values are always present so templates can index them
+	for _, label := range sentimentLabels {
+		c.bySentiment[label] = 0
+	}
+	for level := range riskLevels {
+		c.byRisk[level] = 0
+	}
+
+	for i := range reports {
+		report := &reports[i]
+		c.total++
+		c.scoreSum += report.SentimentScore
+		if report.SentimentLabel != "" {
+			c.bySentiment[report.SentimentLabel]++
+		}
+		if report.RiskLevel != "" {
+			c.byRisk[report.RiskLevel]++
+		}
+
+		var topics []string
+		if report.KeyTopics != "" {
+			json.Unmarshal([]byte(report.KeyTopics), &topics)
+		}
+		seen := make(map[string]bool)
+		for _, topic := range topics {
+			topic = strings.TrimSpace(topic)
+			if topic == "" || seen[topic] {
+				continue
+			}
+			seen[topic] = true
+			c.topics[topic]++
+		}
+	}
+	return c
+}
+
+func (c *counts) averageScore() float64 {
+	if c.total == 0 {
+		return 0
+	}
+	return c.scoreSum / float64(c.total)
+}
+
+// topTopicCounts returns the n topics named by the most reports, with their
+// count in the previous period.
+func topTopicCounts(current, previous map[string]int, n int) []models.TopicCount {
+	topics := make([]models.TopicCount, 0, len(current))
+	for topic, count := range current {
+		topics = append(topics, models.TopicCount{Topic: topic, Count: count, PreviousCount: previous[topic]})
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Count != topics[j].Count {
+			return topics[i].Count > topics[j].Count
+		}
+		return topics[i].Topic < topics[j].Topic
+	})
+	if len(topics) > n {
+		topics = topics[:n]
+	}
+	return topics
+}
+
+// highestRisk returns up to n medium and high risk reports, riskiest first;
+// within a level, the most negative come first.
+func highestRisk(reports []models.Report, n int) []models.VideoRisk {
+	videos := []models.VideoRisk{}
+	for i := range reports {
+		report := &reports[i]
+		if riskLevels[report.RiskLevel] < riskLevels["medium"] {
+			continue
+		}
+		videos = append(videos, models.VideoRisk{
+			VideoID:          report.VideoID,
+			ReportID:         report.ID,
+			OriginalFilename: report.Video.OriginalFilename,
+			RiskLevel:        report.RiskLevel,
+			SentimentScore:   report.SentimentScore,
+			SentimentLabel:   report.SentimentLabel,
+		})
+	}
+	sort.Slice(videos, func(i, j int) bool {
+		if ri, rj := riskLevels[videos[i].RiskLevel], riskLevels[videos[j].RiskLevel]; ri != rj {
+			return ri > rj
+		}
+		return videos[i].SentimentScore < videos[j].SentimentScore
+	})
+	if len(videos) > n {
+		videos = videos[:n]
+	}
+	return videos
+}
@@ -0,0 +1,358 @@
+// Package digest makes periodic summaries of the reports of a user or tenant
+// and sends them through the notification channels.
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/notifier"
+	"opinion-monitor/internal/webhook"
+	"opinion-monitor/pkg/ai"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSendHour     = 8
+	defaultWeekday      = time.Monday
+	defaultTopTopics    = 10
+	defaultTopVideos    = 5
+	defaultPollInterval = time.Minute
+
+	// claimBatch is how many due schedules are picked up per poll.
+	claimBatch = 20
+	// claimLease is how long a claimed schedule is hidden from other pollers
+	// while its digest is made. A run that fails is tried again after it.
+	claimLease = 10 * time.Minute
+)
+
+// SummaryInput is what the digest prompt template is rendered with.
+type SummaryInput struct {
+	Period string // daily or weekly
+	Start  time.Time
+	End    time.Time
+	Stats  *models.DigestStats
+}
+
+// Scheduler runs due digest schedules in the background. Schedules are
+// claimed by moving next_run_at forward by a lease, so several instances can
+// poll the same table, and only moved to the next send time in the
+// transaction that stores the digest.
+type Scheduler struct {
+	db           *gorm.DB
+	summarizer   ai.Summarizer
+	prompts      *ai.PromptStore
+	notifier     *notifier.Notifier
+	webhooks     *webhook.Dispatcher
+	sendHour     int
+	sendMinute   int
+	weekday      time.Weekday
+	topTopics    int
+	topVideos    int
+	pollInterval time.Duration
+}
+
+func NewScheduler(db *gorm.DB, cfg config.DigestsConfig, summarizer ai.Summarizer, prompts *ai.PromptStore, notifications *notifier.Notifier, webhooks *webhook.Dispatcher) *Scheduler {
+	s := &Scheduler{
+		db:           db,
+		summarizer:   summarizer,
+		prompts:      prompts,
+		notifier:     notifications,
+		webhooks:     webhooks,
+		sendHour:     defaultSendHour,
+		weekday:      defaultWeekday,
+		topTopics:    cfg.TopTopics,
+		topVideos:    cfg.TopVideos,
+		pollInterval: defaultPollInterval,
+	}
+
+	if t, err := time.Parse("15:04", cfg.SendAt); err == nil {
+		s.sendHour, s.sendMinute = t.Hour(), t.Minute()
+	}
+	if day, ok := parseWeekday(cfg.Weekday); ok {
+		s.weekday = day
+	}
+	if s.topTopics < 1 {
+		s.topTopics = defaultTopTopics
+	}
+	if s.topVideos < 1 {
+		s.topVideos = defaultTopVideos
+	}
+	if t, err := time.ParseDuration(cfg.PollInterval); err == nil && t > 0 {
+		s.pollInterval = t
+	}
+
+	return s
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// NextRun returns the first send time of period strictly after t, in the
+// server's local time zone.
+func (s *Scheduler) NextRun(period string, t time.Time) time.Time {
+	t = t.Local()
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.sendHour, s.sendMinute, 0, 0, time.Local)
+
+	step := 1
+	if period == models.DigestPeriodWeekly {
+		step = 7
+		next = next.AddDate(0, 0, (int(s.weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(t) {
+		next = next.AddDate(0, 0, step)
+	}
+	return next
+}
+
+// LatestRun returns the last send time of period at or before t.
+func (s *Scheduler) LatestRun(period string, t time.Time) time.Time {
+	if period == models.DigestPeriodWeekly {
+		return s.NextRun(period, t).AddDate(0, 0, -7)
+	}
+	return s.NextRun(period, t).AddDate(0, 0, -1)
+}
+
+// PeriodStart returns the start of the period of the digest sent at end.
+func PeriodStart(period string, end time.Time) time.Time {
+	if period == models.DigestPeriodWeekly {
+		return end.AddDate(0, 0, -7)
+	}
+	return end.AddDate(0, 0, -1)
+}
+
+// Start runs the schedule loop in the background.
+func (s *Scheduler) Start() {
+	go func() {
+		for {
+			s.runDue()
+			time.Sleep(s.pollInterval)
+		}
+	}()
+	log.Printf("Started digest scheduler (daily at %02d:%02d, weekly on %s)", s.sendHour, s.sendMinute, s.weekday)
+}
+
+// runDue makes the digest of every active schedule whose send time has
+// passed. Periods missed while the server was down are skipped; only the
+// latest one is sent. A failed run leaves the schedule due, so it is tried
+// again when its claim lease runs out.
+func (s *Scheduler) runDue() {
+	now := time.Now()
+
+	var due []models.DigestSchedule
+	if err := s.db.Where("active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(claimBatch).
+		Find(&due).Error; err != nil {
+		log.Printf("Warning: failed to load digest schedules: %v", err)
+		return
+	}
+
+	for i := range due {
+		schedule := &due[i]
+		leasedUntil, ok := s.claim(schedule, now)
+		if !ok {
+			continue
+		}
+
+		end := s.LatestRun(schedule.Period, now)
+		_, err := s.generate(schedule, PeriodStart(schedule.Period, end), end, func(tx *gorm.DB) error {
+			return s.advance(tx, schedule, leasedUntil, now)
+		})
+		if err != nil {
+			log.Printf("Warning: failed to make digest for schedule %d, retrying after %s: %v", schedule.ID, leasedUntil.Format(time.RFC3339), err)
+		}
+	}
+}
+
+// claim leases a due schedule by moving its next run past the lease, so
+// another instance polling at the same time does not run it twice. It
+// returns the end of the lease.
+func (s *Scheduler) claim(schedule *models.DigestSchedule, now time.Time) (time.Time, bool) {
+	// Whole seconds, so the value read back from the database compares equal
+	leasedUntil := now.Add(claimLease).Truncate(time.Second)
+	result := s.db.Model(&models.DigestSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+		Update("next_run_at", leasedUntil)
+	return leasedUntil, result.Error == nil && result.RowsAffected == 1
+}
+
+// advance moves a leased schedule to its next send time in the transaction
+// that stores its digest. It fails, rolling the digest back, if the lease ran
+// out and another instance has claimed the schedule since.
+func (s *Scheduler) advance(tx *gorm.DB, schedule *models.DigestSchedule, leasedUntil, now time.Time) error {
+	result := tx.Model(&models.DigestSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, leasedUntil).
+		Updates(map[string]interface{}{"next_run_at": s.NextRun(schedule.Period, now), "last_run_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update digest schedule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("digest schedule %d was claimed again before its digest was saved", schedule.ID)
+	}
+	return nil
+}
+
+// Generate makes the digest of the reports created in [start, end), stores
+// it and sends it to the schedule's channels. A failed executive summary is
+// recorded on the digest and does not stop it from being sent.
+func (s *Scheduler) Generate(schedule *models.DigestSchedule, start, end time.Time) (*models.Digest, error) {
+	return s.generate(schedule, start, end, nil)
+}
+
+// generate is Generate with an extra step run in the transaction that stores
+// the digest.
+func (s *Scheduler) generate(schedule *models.DigestSchedule, start, end time.Time, inTx func(tx *gorm.DB) error) (*models.Digest, error) {
+	stats, err := Aggregate(s.db, schedule, start, end, s.topTopics, s.topVideos)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := models.Digest{
+		UserID:      schedule.UserID,
+		ScheduleID:  schedule.ID,
+		Period:      schedule.Period,
+		Scope:       schedule.Scope,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ReportCount: stats.Total,
+	}
+
+	if schedule.Summary && stats.Total > 0 {
+		summary, err := s.summarize(schedule, SummaryInput{Period: schedule.Period, Start: start, End: end, Stats: stats})
+		if err != nil {
+			log.Printf("Warning: failed to summarize digest of schedule %d: %v", schedule.ID, err)
+			digest.SummaryError = err.Error()
+		}
+		digest.Summary = summary
+	}
+
+	encoded, err := json.Marshal(stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode digest stats: %w", err)
+	}
+	digest.Stats = string(encoded)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&digest).Error; err != nil {
+			return fmt.Errorf("failed to save digest: %w", err)
+		}
+		if inTx != nil {
+			if err := inTx(tx); err != nil {
+				return err
+			}
+		}
+		return s.deliver(tx, schedule, &digest, stats)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifier != nil {
+		s.notifier.Notify()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Notify()
+	}
+	return &digest, nil
+}
+
+// summarize asks the chat model for the executive summary, using the prompt
+// templates of the schedule owner's tenant.
+func (s *Scheduler) summarize(schedule *models.DigestSchedule, input SummaryInput) (string, error) {
+	if s.summarizer == nil || s.prompts == nil {
+		return "", fmt.Errorf("no chat model configured")
+	}
+
+	var owner models.User
+	if err := s.db.Select("id", "tenant").First(&owner, schedule.UserID).Error; err != nil {
+		return "", fmt.Errorf("failed to load user: %w", err)
+	}
+	prompts, err := s.prompts.Resolve("", owner.Tenant)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := prompts.Digest(input)
+	if err != nil {
+		return "", err
+	}
+
+	summary, err := s.summarizer.Summarize(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize digest: %w", err)
+	}
+	return summary, nil
+}
+
+// digestWebhookData is the data of digest.created webhook events.
+type digestWebhookData struct {
+	DigestID    uint                `json:"digest_id"`
+	ScheduleID  uint                `json:"schedule_id"`
+	Name        string              `json:"name"`
+	Period      string              `json:"period"`
+	Scope       string              `json:"scope"`
+	PeriodStart time.Time           `json:"period_start"`
+	PeriodEnd   time.Time           `json:"period_end"`
+	Stats       *models.DigestStats `json:"stats"`
+	Summary     string              `json:"summary,omitempty"`
+}
+
+// deliver queues the digest for the schedule's channels in the transaction
+// that stores it.
+func (s *Scheduler) deliver(tx *gorm.DB, schedule *models.DigestSchedule, digest *models.Digest, stats *models.DigestStats) error {
+	var channels []string
+	for _, ch := range strings.Split(schedule.Channels, ",") {
+		if ch = strings.TrimSpace(ch); ch != "" {
+			channels = append(channels, ch)
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	if schedule.Notifies(models.ChannelWebhook) && s.webhooks != nil {
+		data := digestWebhookData{
+			DigestID:    digest.ID,
+			ScheduleID:  schedule.ID,
+			Name:        schedule.Name,
+			Period:      digest.Period,
+			Scope:       digest.Scope,
+			PeriodStart: digest.PeriodStart,
+			PeriodEnd:   digest.PeriodEnd,
+			Stats:       stats,
+			Summary:     digest.Summary,
+		}
+		if err := s.webhooks.Enqueue(tx, schedule.UserID, models.WebhookEventDigestCreated, data); err != nil {
+			return err
+		}
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	msg, err := notifier.RenderDigest(notifier.DigestData{
+		DigestID: digest.ID,
+		Name:     schedule.Name,
+		Period:   digest.Period,
+		Start:    digest.PeriodStart,
+		End:      digest.PeriodEnd,
+		Stats:    stats,
+		Summary:  digest.Summary,
+		Link:     s.notifier.DigestLink(digest.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return s.notifier.Enqueue(tx, schedule.UserID, channels, msg)
+}
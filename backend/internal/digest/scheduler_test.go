@@ -0,0 +1,43 @@
+package digest
+
+import (
+	"opinion-monitor/internal/models"
+	"testing"
+	"time"
+)
+
+func TestNextAndLatestRun(t *testing.T) {
+	s := &Scheduler{sendHour: 8, sendMinute: 30, weekday: time.Monday}
+	at := func(day, hour, minute int) time.Time {
+		// October 2026: the 12th and 19th are Mondays
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name       string
+		period     string
+		t          time.Time
+		wantNext   time.Time
+		wantLatest time.Time
+	}{
+		{"daily before send time", models.DigestPeriodDaily, at(14, 7, 0), at(14, 8, 30), at(13, 8, 30)},
+		{"daily at send time", models.DigestPeriodDaily, at(14, 8, 30), at(15, 8, 30), at(14, 8, 30)},
+		{"daily after send time", models.DigestPeriodDaily, at(14, 23, 0), at(15, 8, 30), at(14, 8, 30)},
+		{"weekly mid-week", models.DigestPeriodWeekly, at(15, 12, 0), at(19, 8, 30), at(12, 8, 30)},
+		{"weekly on the day, before send time", models.DigestPeriodWeekly, at(19, 8, 0), at(19, 8, 30), at(12, 8, 30)},
+		{"weekly at send time", models.DigestPeriodWeekly, at(19, 8, 30), at(26, 8, 30), at(19, 8, 30)},
+		// Two days late: the latest period is sent, not the oldest
+		{"daily after missed runs", models.DigestPeriodDaily, at(16, 9, 0), at(17, 8, 30), at(16, 8, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.NextRun(tt.period, tt.t); !got.Equal(tt.wantNext) {
+				t.Errorf("NextRun() = %v, want %v", got, tt.wantNext)
+			}
+			if got := s.LatestRun(tt.period, tt.t); !got.Equal(tt.wantLatest) {
+				t.Errorf("LatestRun() = %v, want %v", got, tt.wantLatest)
+			}
+		})
+	}
+}
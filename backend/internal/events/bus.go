@@ -0,0 +1,105 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	JobStarted       Type = "job.started"
+	JobStageStarted  Type = "job.stage_started"
+	JobStageFinished Type = "job.stage_finished"
+	JobCompleted     Type = "job.completed"
+	JobRetrying      Type = "job.retrying" // attempt failed, another one is scheduled
+	JobFailed        Type = "job.failed"   // dead-lettered
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before it starts missing events.
+const subscriberBuffer = 64
+
+// Event describes progress of a job. Fields that do not apply to the event
+// type are left empty.
+type Event struct {
+	ID          uint64    `json:"id"`
+	Type        Type      `json:"type"`
+	UserID      uint      `json:"user_id"`
+	JobID       uint      `json:"job_id"`
+	VideoID     uint      `json:"video_id"`
+	Stage       string    `json:"stage,omitempty"`
+	StageStatus string    `json:"stage_status,omitempty"`
+	Progress    int       `json:"progress"` // 0-100
+	Attempt     int       `json:"attempt,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ReportID    uint      `json:"report_id,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Bus fans job events out to in-process subscribers. Publishing never
+// blocks: a subscriber whose buffer is full misses the event. Nothing is
+// kept, so events published while nobody is subscribed, or by another
+// server instance, are not seen.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID uint64
+	subs   map[*subscription]struct{}
+}
+
+type subscription struct {
+	userID uint
+	ch     chan Event
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*subscription]struct{})}
+}
+
+// Subscribe returns a channel receiving the events of userID. The returned
+// function ends the subscription and closes the channel.
+func (b *Bus) Subscribe(userID uint) (<-chan Event, func()) {
+	return b.subscribe(&subscription{userID: userID})
+}
+
+func (b *Bus) subscribe(sub *subscription) (<-chan Event, func()) {
+	sub.ch = make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// Publish assigns the event an ID and timestamp and delivers it.
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	b.nextID++
+	e.ID = b.nextID
+	b.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.userID != e.UserID {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package fingerprint stores the perceptual fingerprints of videos and finds
+// near-duplicates among them: re-encoded, cropped or watermarked copies
+// whose bytes differ.
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/pkg/video"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxDistance   = 12
+	defaultMinSimilarity = 0.5
+
+	// minBands keeps band values within the 16 bits a key holds them in.
+	minBands = 4
+
+	// maxCandidates bounds how many videos sharing a band are scored.
+	maxCandidates = 500
+)
+
+// ErrNoFingerprint is returned for videos that have not been fingerprinted,
+// such as those still waiting for analysis.
+var ErrNoFingerprint = errors.New("video has no fingerprint")
+
+// Match is a video similar to the one searched for.
+type Match struct {
+	Video         models.Video `json:"video"`
+	Similarity    float64      `json:"similarity"`     // 0-1
+	MatchedFrames int          `json:"matched_frames"` // frames of both videos with a match in the other
+}
+
+type Index struct {
+	db            *gorm.DB
+	maxDistance   int
+	minSimilarity float64
+	tenantScope   bool
+}
+
+func NewIndex(db *gorm.DB, cfg config.FingerprintsConfig) *Index {
+	ix := &Index{
+		db:            db,
+		maxDistance:   defaultMaxDistance,
+		minSimilarity: defaultMinSimilarity,
+		tenantScope:   cfg.Scope == "tenant",
+	}
+	if cfg.MaxDistance > 0 {
+		ix.maxDistance = cfg.MaxDistance
+	}
+	if cfg.MinSimilarity > 0 {
+		ix.minSimilarity = cfg.MinSimilarity
+	}
+	return ix
+}
+
+// MinSimilarity returns the configured default threshold for Similar.
+func (ix *Index) MinSimilarity() float64 {
+	return ix.minSimilarity
+}
+
+// Save replaces the fingerprint of a video and its index bands, which are
+// cut for frame hashes up to maxDistance bits apart.
+func Save(tx *gorm.DB, videoID uint, hashes []uint64, maxDistance int) error {
+	encoded := make([]string, len(hashes))
+	for i, h := range hashes {
+		encoded[i] = fmt.Sprintf("%016x", h)
+	}
+
+	if err := tx.Where("video_id = ?", videoID).Delete(&models.VideoFingerprint{}).Error; err != nil {
+		return fmt.Errorf("failed to replace fingerprint: %w", err)
+	}
+
+	fp := models.VideoFingerprint{
+		VideoID: videoID,
+		Hashes:  strings.Join(encoded, ","),
+		Frames:  len(hashes),
+	}
+	if err := tx.Create(&fp).Error; err != nil {
+		return fmt.Errorf("failed to save fingerprint: %w", err)
+	}
+	return saveBands(tx, videoID, hashes, bandCount(maxDistance))
+}
+
+func saveBands(tx *gorm.DB, videoID uint, hashes []uint64, bands int) error {
+	if err := tx.Where("video_id = ?", videoID).Delete(&models.FingerprintBand{}).Error; err != nil {
+		return fmt.Errorf("failed to replace fingerprint: %w", err)
+	}
+
+	keys := bandKeys(hashes, bands)
+	if len(keys) == 0 {
+		return nil
+	}
+	rows := make([]models.FingerprintBand, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, models.FingerprintBand{VideoID: videoID, Band: key})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to save fingerprint: %w", err)
+	}
+	return nil
+}
+
+// Reindex recuts the bands of fingerprints indexed for another max distance,
+// such as before max_distance was changed, and returns how many it redid.
+func (ix *Index) Reindex() (int, error) {
+	bands := bandCount(ix.maxDistance)
+	var videoIDs []uint
+	if err := ix.db.Model(&models.FingerprintBand{}).
+		Where("band < ? OR band >= ?", int64(bands)<<24, int64(bands+1)<<24).
+		Distinct("video_id").
+		Pluck("video_id", &videoIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find stale fingerprint bands: %w", err)
+	}
+
+	for _, videoID := range videoIDs {
+		hashes, err := ix.load(videoID)
+		if errors.Is(err, ErrNoFingerprint) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := ix.db.Transaction(func(tx *gorm.DB) error {
+			return saveBands(tx, videoID, hashes, bands)
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(videoIDs), nil
+}
+
+// Similar lists the videos whose fingerprints score at least minSimilarity
+// against videoRecord's, most similar first. Only videos of the same owner
+// are searched, or of the owner's tenant when the scope is tenant.
+func (ix *Index) Similar(videoRecord *models.Video, minSimilarity float64, limit int) ([]Match, error) {
+	hashes, err := ix.load(videoRecord.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return []Match{}, nil
+	}
+
+	owners, err := ix.owners(videoRecord.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Videos sharing the most bands are the likeliest matches
+	var candidateIDs []uint
+	if err := ix.db.Model(&models.FingerprintBand{}).
+		Where("band IN ? AND video_id <> ?", bandKeys(hashes, bandCount(ix.maxDistance)), videoRecord.ID).
+		Where("video_id IN (?)", ix.db.Model(&models.Video{}).Select("id").Where("user_id IN (?)", owners)).
+		Group("video_id").
+		Order("COUNT(*) DESC").
+		Limit(maxCandidates).
+		Pluck("video_id", &candidateIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to search fingerprints: %w", err)
+	}
+	if len(candidateIDs) == 0 {
+		return []Match{}, nil
+	}
+
+	var fingerprints []models.VideoFingerprint
+	if err := ix.db.Where("video_id IN ?", candidateIDs).Find(&fingerprints).Error; err != nil {
+		return nil, fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	scores := make(map[uint]Match)
+	for _, fp := range fingerprints {
+		similarity, matched := video.Similarity(hashes, parseHashes(fp.Hashes), ix.maxDistance)
+		if similarity >= minSimilarity {
+			scores[fp.VideoID] = Match{Similarity: similarity, MatchedFrames: matched}
+		}
+	}
+	if len(scores) == 0 {
+		return []Match{}, nil
+	}
+
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	var videos []models.Video
+	if err := ix.db.Where("id IN ?", ids).
+		Preload("User").
+		Preload("Report", "is_current = ?", true).
+		Find(&videos).Error; err != nil {
+		return nil, fmt.Errorf("failed to load videos: %w", err)
+	}
+
+	matches := make([]Match, 0, len(videos))
+	for _, v := range videos {
+		m := scores[v.ID]
+		m.Video = v
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].Video.ID < matches[j].Video.ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (ix *Index) load(videoID uint) ([]uint64, error) {
+	var fp models.VideoFingerprint
+	if err := ix.db.Where("video_id = ?", videoID).First(&fp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoFingerprint
+		}
+		return nil, fmt.Errorf("failed to load fingerprint: %w", err)
+	}
+	return parseHashes(fp.Hashes), nil
+}
+
+// owners returns a subquery of the users whose videos userID searches.
+func (ix *Index) owners(userID uint) (*gorm.DB, error) {
+	if ix.tenantScope {
+		var user models.User
+		if err := ix.db.Select("id", "tenant").First(&user, userID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load user: %w", err)
+		}
+		if user.Tenant != "" {
+			return ix.db.Model(&models.User{}).Select("id").Where("tenant = ?", user.Tenant), nil
+		}
+	}
+	return ix.db.Model(&models.User{}).Select("id").Where("id = ?", userID), nil
+}
+
+// bandCount returns how many bands hashes are cut into so that any two at
+// most maxDistance bits apart share one: differing bits fall in at most
+// maxDistance of maxDistance+1 bands, leaving one equal.
+func bandCount(maxDistance int) int {
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxDistance
+	}
+	bands := maxDistance + 1
+	if bands < minBands {
+		bands = minBands
+	}
+	if bands > 64 {
+		bands = 64
+	}
+	return bands
+}
+
+// bandKeys cuts every hash into bands of nearly equal width and numbers
+// them so that equal values in different positions, or under another band
+// count, do not collide: bands << 24 | band << 16 | value.
+func bandKeys(hashes []uint64, bands int) []int64 {
+	seen := make(map[int64]bool, len(hashes)*bands)
+	keys := make([]int64, 0, len(hashes)*bands)
+	for _, h := range hashes {
+		shift := 0
+		for band := 0; band < bands; band++ {
+			width := 64 / bands
+			if band < 64%bands {
+				width++
+			}
+			value := (h >> uint(shift)) & (1<<uint(width) - 1)
+			shift += width
+
+			key := int64(bands)<<24 | int64(band)<<16 | int64(value)
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func parseHashes(s string) []uint64 {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	hashes := make([]uint64, 0, len(parts))
+	for _, p := range parts {
+		if h, err := strconv.ParseUint(p, 16, 64); err == nil {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,174 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/pkg/video"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 10 * time.Second
+
+	// rejectedDir holds dropped files that could not be ingested, so they
+	// are not picked up again.
+	rejectedDir = "rejected"
+)
+
+// Suffixes of files that are still being written by common copy tools.
+var partialSuffixes = []string{".part", ".partial", ".tmp", ".crdownload", ".download"}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// DropFolder ingests the video files placed in a directory for one user. A
+// file is picked up once its size and modification time are unchanged
+// between two scans, then moved into storage; files that are not videos are
+// moved to the rejected subdirectory, and files that fail for other reasons
+// are left in place to be tried again.
+type DropFolder struct {
+	ingester *Ingester
+	dir      string
+	userID   uint
+	interval time.Duration
+	pending  map[string]fileState
+}
+
+func NewDropFolder(ingester *Ingester, cfg config.ImportsConfig, userID uint) *DropFolder {
+	d := &DropFolder{
+		ingester: ingester,
+		dir:      cfg.WatchDir,
+		userID:   userID,
+		interval: defaultWatchInterval,
+		pending:  make(map[string]fileState),
+	}
+	if t, err := time.ParseDuration(cfg.WatchInterval); err == nil && t > 0 {
+		d.interval = t
+	}
+	return d
+}
+
+// Start scans the folder in the background.
+func (d *DropFolder) Start() error {
+	if err := os.MkdirAll(filepath.Join(d.dir, rejectedDir), 0755); err != nil {
+		return fmt.Errorf("failed to create drop folder: %w", err)
+	}
+
+	go func() {
+		for {
+			d.scan()
+			time.Sleep(d.interval)
+		}
+	}()
+	log.Printf("Watching drop folder %s for user %d", d.dir, d.userID)
+	return nil
+}
+
+func (d *DropFolder) scan() {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		log.Printf("Warning: failed to read drop folder: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || isPartialFile(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		seen[name] = true
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if prev, ok := d.pending[name]; !ok || prev != state {
+			// New or still growing; look again on the next scan
+			d.pending[name] = state
+			continue
+		}
+
+		delete(d.pending, name)
+		err = d.ingest(name, state.size)
+		switch {
+		case errors.Is(err, ErrNotVideo), errors.Is(err, video.ErrInvalidVideo):
+			log.Printf("Warning: rejected dropped file %s: %v", name, err)
+			d.reject(name)
+		case err != nil:
+			// Storage or database trouble; the file stays and is tried again
+			log.Printf("Warning: failed to ingest dropped file %s, will retry: %v", name, err)
+		}
+	}
+
+	for name := range d.pending {
+		if !seen[name] {
+			delete(d.pending, name)
+		}
+	}
+}
+
+func (d *DropFolder) ingest(name string, size int64) error {
+	src := filepath.Join(d.dir, name)
+	if !video.IsVideoFile(name) {
+		return ErrNotVideo
+	}
+
+	filePath, err := d.ingester.StoragePath(d.userID, name)
+	if err != nil {
+		return err
+	}
+	if err := moveFile(src, filePath); err != nil {
+		return err
+	}
+
+	videoRecord, err := d.ingester.Ingest(d.userID, name, filePath, size)
+	var duplicate *DuplicateError
+	if errors.As(err, &duplicate) {
+		os.Remove(filePath)
+		log.Printf("Dropped file %s is a duplicate of video %d", name, duplicate.Video.ID)
+		return nil
+	}
+	if err != nil {
+		// Put the file back to be rejected or retried
+		if moveErr := moveFile(filePath, src); moveErr != nil {
+			log.Printf("Warning: failed to restore dropped file %s: %v", name, moveErr)
+		}
+		return err
+	}
+
+	log.Printf("Ingested dropped file %s as video %d", name, videoRecord.ID)
+	return nil
+}
+
+func (d *DropFolder) reject(name string) {
+	src := filepath.Join(d.dir, name)
+	if _, err := os.Stat(src); err != nil {
+		return
+	}
+	dst := filepath.Join(d.dir, rejectedDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), name))
+	if err := moveFile(src, dst); err != nil {
+		log.Printf("Warning: failed to move rejected file %s: %v", name, err)
+	}
+}
+
+func isPartialFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
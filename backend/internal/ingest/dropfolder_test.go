@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"opinion-monitor/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDropFolder returns a drop folder watching a temp directory, which is
+// returned too, and storing files under uploadDir.
+func newTestDropFolder(t *testing.T, uploadDir string) (*DropFolder, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, rejectedDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return NewDropFolder(&Ingester{uploadPath: uploadDir}, config.ImportsConfig{WatchDir: dir}, 1), dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func rejectedCount(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, rejectedDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestDropFolderWaitsForStableFiles(t *testing.T) {
+	d, dir := newTestDropFolder(t, t.TempDir())
+	path := filepath.Join(dir, "notes.txt")
+	writeFile(t, path, "first")
+
+	d.scan()
+	if !exists(path) {
+		t.Fatalf("file was picked up on the scan that first saw it")
+	}
+
+	// Still being written: the size changed since the last scan
+	writeFile(t, path, "first and second")
+	d.scan()
+	if !exists(path) {
+		t.Fatalf("file was picked up while it was growing")
+	}
+
+	d.scan()
+	if exists(path) {
+		t.Fatalf("file was not picked up after two unchanged scans")
+	}
+	if got := rejectedCount(t, dir); got != 1 {
+		t.Fatalf("rejected files = %d, want the text file", got)
+	}
+}
+
+func TestDropFolderSkipsPartialFiles(t *testing.T) {
+	d, dir := newTestDropFolder(t, t.TempDir())
+	names := []string{"clip.mp4.part", "clip.MP4.crdownload", "upload.tmp", ".hidden.mp4"}
+	for _, name := range names {
+		writeFile(t, filepath.Join(dir, name), "partial")
+	}
+
+	for i := 0; i < 3; i++ {
+		d.scan()
+	}
+	for _, name := range names {
+		if !exists(filepath.Join(dir, name)) {
+			t.Errorf("%s was picked up", name)
+		}
+	}
+	if len(d.pending) != 0 {
+		t.Errorf("pending = %v, want partial files ignored", d.pending)
+	}
+}
+
+func TestDropFolderRejectsNonVideoContent(t *testing.T) {
+	uploadDir := t.TempDir()
+	d, dir := newTestDropFolder(t, uploadDir)
+	path := filepath.Join(dir, "clip.mp4")
+	writeFile(t, path, "<html>not a video</html>")
+
+	d.scan()
+	d.scan()
+	if exists(path) {
+		t.Fatalf("file with a video name but other content was left in place")
+	}
+	if got := rejectedCount(t, dir); got != 1 {
+		t.Fatalf("rejected files = %d, want 1", got)
+	}
+	if files := storedFiles(t, uploadDir); len(files) > 0 {
+		t.Fatalf("rejected file left %v in storage", files)
+	}
+}
+
+func TestDropFolderRetriesOtherFailures(t *testing.T) {
+	// Storage cannot be created under a regular file
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	writeFile(t, blocked, "")
+	d, dir := newTestDropFolder(t, blocked)
+	path := filepath.Join(dir, "clip.mp4")
+	writeFile(t, path, string(mp4Head))
+
+	for i := 0; i < 4; i++ {
+		d.scan()
+	}
+	if !exists(path) {
+		t.Fatalf("file was moved although it failed for a reason other than its content")
+	}
+	if got := rejectedCount(t, dir); got != 0 {
+		t.Fatalf("rejected files = %d, want 0", got)
+	}
+}
@@ -0,0 +1,150 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeUploadsDB is an in-memory uploads table behind a database/sql driver,
+// for testing Uploads without MySQL. It understands the statements GORM
+// builds for loading, updating and deleting an upload by id.
+type fakeUploadsDB struct {
+	mu   sync.Mutex
+	rows map[int64]map[string]driver.Value
+}
+
+var uploadColumns = []string{
+	"id", "user_id", "filename", "size", "upload_offset", "checksum", "staging_path",
+	"status", "video_id", "expires_at", "created_at", "updated_at",
+}
+
+var (
+	selectUploadRe = regexp.MustCompile("^SELECT \\* FROM `uploads` WHERE `uploads`.`id` = \\?")
+	updateUploadRe = regexp.MustCompile("^UPDATE `uploads` SET (.+) WHERE `(?:uploads`.`)?id` = \\?")
+	deleteUploadRe = regexp.MustCompile("^DELETE FROM `uploads` WHERE `uploads`.`id` = \\?")
+	setColumnRe    = regexp.MustCompile("`(\\w+)`=\\?")
+)
+
+// newFakeUploadsDB returns the fake table and a GORM handle on it.
+func newFakeUploadsDB(t *testing.T) (*fakeUploadsDB, *gorm.DB) {
+	t.Helper()
+	fake := &fakeUploadsDB{rows: make(map[int64]map[string]driver.Value)}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(fake),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	return fake, db
+}
+
+// row returns a copy of the upload with the given id, or nil.
+func (f *fakeUploadsDB) row(id int64) map[string]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row, ok := f.rows[id]
+	if !ok {
+		return nil
+	}
+	copied := make(map[string]driver.Value, len(row))
+	for k, v := range row {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (f *fakeUploadsDB) insert(row map[string]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows[row["id"].(int64)] = row
+}
+
+func (f *fakeUploadsDB) set(id int64, column string, value driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows[id][column] = value
+}
+
+func (f *fakeUploadsDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeUploadsDB) Open(string) (driver.Conn, error)             { return &fakeConn{db: f}, nil }
+func (f *fakeUploadsDB) Driver() driver.Driver                        { return f }
+
+type fakeConn struct {
+	db *fakeUploadsDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake database does not prepare statements")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !selectUploadRe.MatchString(query) {
+		return nil, fmt.Errorf("fake database cannot run %q", query)
+	}
+	rows := &fakeRows{}
+	if row := c.db.row(args[0].Value.(int64)); row != nil {
+		values := make([]driver.Value, len(uploadColumns))
+		for i, col := range uploadColumns {
+			values[i] = row[col]
+		}
+		rows.values = append(rows.values, values)
+	}
+	return rows, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if m := updateUploadRe.FindStringSubmatch(query); m != nil {
+		columns := setColumnRe.FindAllStringSubmatch(m[1], -1)
+		row, ok := c.db.rows[args[len(columns)].Value.(int64)]
+		if !ok {
+			return driver.RowsAffected(0), nil
+		}
+		for i, col := range columns {
+			row[col[1]] = args[i].Value
+		}
+		return driver.RowsAffected(1), nil
+	}
+	if deleteUploadRe.MatchString(query) {
+		id := args[0].Value.(int64)
+		if _, ok := c.db.rows[id]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		delete(c.db.rows, id)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("fake database cannot run %q", query)
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return uploadColumns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
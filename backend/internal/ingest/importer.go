@@ -0,0 +1,171 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/netguard"
+	"opinion-monitor/pkg/video"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultImportMaxSize = 2 << 30
+	defaultImportTimeout = 10 * time.Minute
+
+	defaultImportRequestTimeout = 15 * time.Minute
+)
+
+var (
+	ErrUnsupportedURL = errors.New("only http and https URLs can be imported")
+	ErrPrivateAddress = netguard.ErrPrivateAddress
+	ErrDownloadFailed = errors.New("download failed")
+	ErrFileTooLarge   = errors.New("file is too large")
+)
+
+// Importer downloads videos from URLs and ingests them.
+type Importer struct {
+	ingester       *Ingester
+	httpClient     *http.Client
+	maxSize        int64
+	requestTimeout time.Duration
+}
+
+func NewImporter(ingester *Ingester, cfg config.ImportsConfig) *Importer {
+	timeout := defaultImportTimeout
+	if t, err := time.ParseDuration(cfg.Timeout); err == nil && t > 0 {
+		timeout = t
+	}
+	requestTimeout := defaultImportRequestTimeout
+	if t, err := time.ParseDuration(cfg.RequestTimeout); err == nil && t > 0 {
+		requestTimeout = t
+	}
+	maxSize := cfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultImportMaxSize
+	}
+
+	return &Importer{
+		ingester:       ingester,
+		httpClient:     netguard.NewClient(timeout, cfg.AllowPrivateNetworks, true),
+		maxSize:        maxSize,
+		requestTimeout: requestTimeout,
+	}
+}
+
+// RequestContext returns the context an import request runs its URLs in. It
+// ends when parent does, e.g. because the client went away, or once the
+// request has taken imports.request_timeout.
+func (im *Importer) RequestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, im.requestTimeout)
+}
+
+// Import downloads the video at rawURL and ingests it for userID. The
+// content is identified by its leading bytes, not by the URL or the
+// Content-Type header. The download stops when ctx is done.
+func (im *Importer) Import(ctx context.Context, userID uint, rawURL string) (*models.Video, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrUnsupportedURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	resp, err := im.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) {
+			return nil, ErrPrivateAddress
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: server returned status %d", ErrDownloadFailed, resp.StatusCode)
+	}
+	if resp.ContentLength > im.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrFileTooLarge, resp.ContentLength, im.maxSize)
+	}
+
+	head := make([]byte, video.SniffLength)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	head = head[:n]
+	ext, ok := video.SniffContainer(head)
+	if !ok {
+		return nil, fmt.Errorf("%w (detected %s)", ErrNotVideo, http.DetectContentType(head))
+	}
+
+	filename := importFilename(u, resp.Header.Get("Content-Disposition"))
+	if !video.IsVideoFile(filename) {
+		filename += ext
+	}
+
+	filePath, err := im.ingester.StoragePath(userID, filename)
+	if err != nil {
+		return nil, err
+	}
+	size, err := saveDownload(filePath, head, resp.Body, im.maxSize)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	videoRecord, err := im.ingester.Ingest(userID, filename, filePath, size)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	return videoRecord, nil
+}
+
+// saveDownload writes head and the rest of body to filePath, failing once
+// more than maxSize bytes have been received.
+func saveDownload(filePath string, head []byte, body io.Reader, maxSize int64) (int64, error) {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(head); err != nil {
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	rest, err := io.Copy(f, io.LimitReader(body, maxSize-int64(len(head))+1))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	size := int64(len(head)) + rest
+	if size > maxSize {
+		return 0, fmt.Errorf("%w: the limit is %d bytes", ErrFileTooLarge, maxSize)
+	}
+	return size, nil
+}
+
+// importFilename names an imported file after its Content-Disposition
+// header, or else the last segment of the URL path.
+func importFilename(u *url.URL, contentDisposition string) string {
+	if _, params, err := mime.ParseMediaType(contentDisposition); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], "\\", "/")); name != "." && name != "/" && name != "" {
+			return name
+		}
+	}
+	if name := path.Base(u.Path); name != "." && name != "/" && name != "" {
+		return name
+	}
+	return "import"
+}
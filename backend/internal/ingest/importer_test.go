@@ -0,0 +1,157 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"opinion-monitor/internal/config"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mp4Head starts like an MP4 file.
+var mp4Head = []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2")
+
+// newTestImporter returns an importer storing files under a temp directory,
+// which is returned too.
+func newTestImporter(t *testing.T, allowPrivate bool, maxSize int64) (*Importer, string) {
+	t.Helper()
+	uploadDir := t.TempDir()
+	im := NewImporter(&Ingester{uploadPath: uploadDir}, config.ImportsConfig{
+		MaxSize:              maxSize,
+		Timeout:              "5s",
+		AllowPrivateNetworks: allowPrivate,
+	})
+	return im, uploadDir
+}
+
+// storedFiles lists the regular files under dir.
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", dir, err)
+	}
+	return files
+}
+
+func TestImportRefusals(t *testing.T) {
+	body := append(append([]byte{}, mp4Head...), bytes.Repeat([]byte{0}, 2000)...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sized.mp4", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	})
+	mux.HandleFunc("/streamed.mp4", func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before the end makes the response chunked, without a length
+		w.Write(body[:100])
+		w.(http.Flusher).Flush()
+		w.Write(body[100:])
+	})
+	mux.HandleFunc("/page.mp4", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write([]byte("<!DOCTYPE html><html><body>not a video</body></html>"))
+	})
+	mux.HandleFunc("/audio.m4a", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\x00\x00\x00\x18ftypM4A \x00\x00\x02\x00"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/to-file", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		allowPrivate bool
+		want         error
+	}{
+		{"too large by content length", server.URL + "/sized.mp4", true, ErrFileTooLarge},
+		{"too large while streaming", server.URL + "/streamed.mp4", true, ErrFileTooLarge},
+		{"page sent as video", server.URL + "/page.mp4", true, ErrNotVideo},
+		{"audio-only mp4 brand", server.URL + "/audio.m4a", true, ErrNotVideo},
+		{"redirect loop", server.URL + "/loop", true, ErrDownloadFailed},
+		{"redirect to another scheme", server.URL + "/to-file", true, ErrDownloadFailed},
+		{"error status", server.URL + "/missing", true, ErrDownloadFailed},
+		{"loopback address", server.URL + "/sized.mp4", false, ErrPrivateAddress},
+		{"unsupported scheme", "ftp://example.com/video.mp4", true, ErrUnsupportedURL},
+		{"no host", "http:///video.mp4", true, ErrUnsupportedURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im, uploadDir := newTestImporter(t, tt.allowPrivate, 1000)
+
+			_, err := im.Import(context.Background(), 1, tt.url)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Import() error = %v, want %v", err, tt.want)
+			}
+			if files := storedFiles(t, uploadDir); len(files) > 0 {
+				t.Fatalf("Import() left %v behind", files)
+			}
+		})
+	}
+}
+
+func TestImportStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(mp4Head)
+	}))
+	defer server.Close()
+
+	im, _ := newTestImporter(t, true, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := im.Import(ctx, 1, server.URL+"/video.mp4")
+	if !errors.Is(err, ErrDownloadFailed) || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("Import() error = %v, want a cancelled download", err)
+	}
+}
+
+func TestImportFilename(t *testing.T) {
+	tests := []struct {
+		name               string
+		url                string
+		contentDisposition string
+		want               string
+	}{
+		{"from url", "https://example.com/media/clip.mp4?x=1", "", "clip.mp4"},
+		{"from header", "https://example.com/download?id=3", `attachment; filename="news.webm"`, "news.webm"},
+		{"header path is dropped", "https://example.com/d", `attachment; filename="..\\..\\evil.mp4"`, "evil.mp4"},
+		{"nothing to go on", "https://example.com/", "", "import"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := importFilename(u, tt.contentDisposition); got != tt.want {
+				t.Fatalf("importFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
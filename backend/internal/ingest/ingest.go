@@ -0,0 +1,240 @@
+// Package ingest turns received video files into videos queued for analysis.
+// Every way a video enters the system goes through Ingester.Ingest.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/worker"
+	"opinion-monitor/pkg/video"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotVideo is returned for content that does not start like a supported
+// video container, whatever its name.
+var ErrNotVideo = errors.New("content is not a supported video")
+
+// Deduplication modes and scopes, see config.DedupConfig.
+const (
+	DedupLink        = "link"  // a copy of the user's own video is not ingested again
+	DedupReuse       = "reuse" // a copy becomes a new video reusing the original's analysis
+	DedupOff         = "off"
+	DedupScopeUser   = "user"
+	DedupScopeTenant = "tenant"
+)
+
+// DuplicateError is returned by Ingest in link mode when the user already has
+// a video with the same content.
+type DuplicateError struct {
+	Video *models.Video
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("file is a duplicate of video %d", e.Video.ID)
+}
+
+type Ingester struct {
+	db         *gorm.DB
+	uploadPath string
+	jobQueue   *worker.JobQueue
+	dedupMode  string
+	dedupScope string
+}
+
+func NewIngester(db *gorm.DB, uploadPath string, jobQueue *worker.JobQueue, dedup config.DedupConfig) *Ingester {
+	in := &Ingester{
+		db:         db,
+		uploadPath: uploadPath,
+		jobQueue:   jobQueue,
+		dedupMode:  DedupLink,
+		dedupScope: DedupScopeUser,
+	}
+	if dedup.Mode == DedupReuse || dedup.Mode == DedupOff {
+		in.dedupMode = dedup.Mode
+	}
+	if dedup.Scope == DedupScopeTenant {
+		in.dedupScope = DedupScopeTenant
+	}
+	return in
+}
+
+// StoragePath returns a new path for a file of userID, in a directory per
+// user and day that is created if needed.
+func (in *Ingester) StoragePath(userID uint, originalFilename string) (string, error) {
+	userDir := filepath.Join(in.uploadPath, fmt.Sprintf("%d", userID), time.Now().Format("2006-01-02"))
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(originalFilename))
+	return filepath.Join(userDir, filename), nil
+}
+
+// Ingest validates the file stored at filePath, normally a path from
+// StoragePath, and creates the video and its analysis job. Files that are
+// not videos fail with ErrNotVideo or video.ErrInvalidVideo.
+//
+// A file with the same content as an earlier video is deduplicated: in link
+// mode a copy of the user's own video fails with a *DuplicateError naming
+// it, and otherwise a copy of an analyzed video in scope gets a job that
+// reuses the original's analysis instead of running the models again. On
+// error the file is left in place for the caller to remove or move back.
+func (in *Ingester) Ingest(userID uint, originalFilename, filePath string, size int64) (*models.Video, error) {
+	info, err := Validate(filePath)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := fileSHA256(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.dedupMode == DedupLink {
+		var existing models.Video
+		err := in.db.Where("user_id = ? AND content_hash = ? AND status <> ?", userID, hash, models.StatusFailed).
+			Order("id ASC").First(&existing).Error
+		if err == nil {
+			return nil, &DuplicateError{Video: &existing}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to look up duplicates: %w", err)
+		}
+	}
+	original, err := in.analyzedCopy(userID, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	videoRecord := models.Video{
+		UserID:           userID,
+		OriginalFilename: originalFilename,
+		FilePath:         filePath,
+		FileSize:         size,
+		Duration:         info.Duration,
+		ContentHash:      hash,
+		Status:           models.StatusPending,
+	}
+
+	err = in.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&videoRecord).Error; err != nil {
+			return fmt.Errorf("failed to create video: %w", err)
+		}
+		job := models.Job{
+			VideoID: videoRecord.ID,
+			Status:  models.JobStatusPending,
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+
+		if original == nil {
+			return nil
+		}
+		reused, err := worker.ReuseAnalysis(tx, original, &videoRecord, job.ID)
+		if err != nil || !reused {
+			return err
+		}
+		videoRecord.DuplicateOfID = &original.ID
+		return tx.Model(&videoRecord).Update("duplicate_of_id", original.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Wake up a worker; the job row itself is the queue entry
+	in.jobQueue.Notify()
+
+	return &videoRecord, nil
+}
+
+// analyzedCopy finds the oldest completed video with the given content that
+// userID may reuse the analysis of, or nil.
+func (in *Ingester) analyzedCopy(userID uint, hash string) (*models.Video, error) {
+	if in.dedupMode == DedupOff {
+		return nil, nil
+	}
+
+	// Users without a tenant only share with themselves
+	tenant := ""
+	if in.dedupScope == DedupScopeTenant {
+		var user models.User
+		if err := in.db.Select("id", "tenant").First(&user, userID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load user: %w", err)
+		}
+		tenant = user.Tenant
+	}
+
+	query := in.db.Where("content_hash = ? AND status = ?", hash, models.StatusCompleted)
+	if tenant != "" {
+		query = query.Where("user_id IN (?)", in.db.Model(&models.User{}).Select("id").Where("tenant = ?", tenant))
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var original models.Video
+	err := query.Order("id ASC").First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up duplicates: %w", err)
+	}
+	return &original, nil
+}
+
+// Validate checks that a file is a video by its leading bytes, then by
+// probing it with ffprobe, and returns what the probe found.
+func Validate(filePath string) (*video.ProbeInfo, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	head := make([]byte, video.SniffLength)
+	n, err := io.ReadFull(f, head)
+	f.Close()
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, ok := video.SniffContainer(head[:n]); !ok {
+		return nil, ErrNotVideo
+	}
+
+	return video.NewProcessor().Probe(filePath)
+}
+
+// moveFile renames src to dst, copying when they are on different filesystems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	os.Remove(src)
+	return nil
+}
@@ -0,0 +1,341 @@
+package ingest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/pkg/video"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUploadExpiry = 24 * time.Hour
+	defaultMaxChunkSize = 64 << 20
+	sweepInterval       = time.Hour
+)
+
+var (
+	ErrUploadClosed      = errors.New("upload is already completed")
+	ErrUploadBusy        = errors.New("upload is being written by another request")
+	ErrOffsetMismatch    = errors.New("offset does not match the upload offset")
+	ErrChunkTooLarge     = errors.New("chunk exceeds the remaining upload length or the maximum chunk size")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrUploadIncomplete  = errors.New("upload is incomplete")
+	ErrUploadInterrupted = errors.New("upload was interrupted")
+)
+
+// Uploads manages resumable uploads: a file is declared with its length,
+// sent in chunks appended at the current offset, and turned into a video by
+// Complete once all of it has arrived. Chunks of an upload are written one
+// at a time; staging files live on local disk, so requests for an upload
+// must reach the instance that created it.
+type Uploads struct {
+	db           *gorm.DB
+	ingester     *Ingester
+	stagingDir   string
+	maxSize      int64
+	maxChunkSize int64
+	expiry       time.Duration
+
+	mu   sync.Mutex
+	busy map[uint]bool
+}
+
+func NewUploads(db *gorm.DB, ingester *Ingester, cfg config.UploadsConfig) *Uploads {
+	u := &Uploads{
+		db:           db,
+		ingester:     ingester,
+		stagingDir:   cfg.StagingDir,
+		maxSize:      cfg.MaxSize,
+		maxChunkSize: cfg.MaxChunkSize,
+		expiry:       defaultUploadExpiry,
+		busy:         make(map[uint]bool),
+	}
+
+	if u.maxChunkSize <= 0 {
+		u.maxChunkSize = defaultMaxChunkSize
+	}
+	if t, err := time.ParseDuration(cfg.Expiry); err == nil && t > 0 {
+		u.expiry = t
+	}
+
+	return u
+}
+
+// MaxSize returns the largest file accepted, 0 meaning no limit.
+func (u *Uploads) MaxSize() int64 {
+	return u.maxSize
+}
+
+// Create declares an upload of size bytes and creates its empty staging file.
+// checksum is the expected SHA-256 of the whole file in hex, or empty.
+func (u *Uploads) Create(userID uint, filename string, size int64, checksum string) (*models.Upload, error) {
+	if err := os.MkdirAll(u.stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	upload := models.Upload{
+		UserID:    userID,
+		Filename:  filename,
+		Size:      size,
+		Checksum:  checksum,
+		Status:    models.UploadStatusUploading,
+		ExpiresAt: time.Now().Add(u.expiry),
+	}
+	if err := u.db.Create(&upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	upload.StagingPath = filepath.Join(u.stagingDir, fmt.Sprintf("%d_%d.part", upload.UserID, upload.ID))
+	f, err := os.Create(upload.StagingPath)
+	if err != nil {
+		u.db.Delete(&upload)
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	f.Close()
+
+	if err := u.db.Model(&upload).Update("staging_path", upload.StagingPath).Error; err != nil {
+		os.Remove(upload.StagingPath)
+		u.db.Delete(&upload)
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return &upload, nil
+}
+
+// Append writes a chunk at offset, which must be the upload's current
+// offset, and returns the new offset. When chunkSum (the chunk's SHA-256) is
+// given, a chunk that does not match is discarded. Without it, the bytes
+// received before a dropped connection are kept so the client can resume
+// from there.
+func (u *Uploads) Append(upload *models.Upload, offset int64, body io.Reader, chunkSum []byte) (int64, error) {
+	if !u.lock(upload.ID) {
+		return 0, ErrUploadBusy
+	}
+	defer u.unlock(upload.ID)
+
+	// Reload under the lock; the caller's copy may be stale
+	if err := u.db.First(upload, upload.ID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load upload: %w", err)
+	}
+	if upload.Status != models.UploadStatusUploading {
+		return upload.Offset, ErrUploadClosed
+	}
+	if offset != upload.Offset {
+		return upload.Offset, ErrOffsetMismatch
+	}
+
+	limit := upload.Size - upload.Offset
+	if limit > u.maxChunkSize {
+		limit = u.maxChunkSize
+	}
+
+	f, err := os.OpenFile(upload.StagingPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return upload.Offset, fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer f.Close()
+
+	// Drop anything past the offset left by an earlier failed write
+	if err := f.Truncate(upload.Offset); err != nil {
+		return upload.Offset, fmt.Errorf("failed to prepare staging file: %w", err)
+	}
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return upload.Offset, fmt.Errorf("failed to prepare staging file: %w", err)
+	}
+
+	hash := sha256.New()
+	written, copyErr := io.Copy(io.MultiWriter(f, hash), io.LimitReader(body, limit+1))
+
+	discard := func(err error) (int64, error) {
+		f.Truncate(upload.Offset)
+		return upload.Offset, err
+	}
+	if written > limit {
+		return discard(ErrChunkTooLarge)
+	}
+	if chunkSum != nil && (copyErr != nil || !bytes.Equal(hash.Sum(nil), chunkSum)) {
+		if copyErr != nil {
+			return discard(fmt.Errorf("%w: %v", ErrUploadInterrupted, copyErr))
+		}
+		return discard(ErrChecksumMismatch)
+	}
+
+	newOffset := upload.Offset + written
+	if err := u.db.Model(upload).Updates(map[string]interface{}{
+		"upload_offset": newOffset,
+		"expires_at":    time.Now().Add(u.expiry),
+	}).Error; err != nil {
+		return discard(fmt.Errorf("failed to update upload: %w", err))
+	}
+	upload.Offset = newOffset
+
+	if copyErr != nil {
+		return newOffset, fmt.Errorf("%w: %v", ErrUploadInterrupted, copyErr)
+	}
+	return newOffset, nil
+}
+
+// Complete checks that every byte has arrived and matches the declared
+// checksum, then moves the file into storage and creates the video and its
+// job. An upload whose checksum does not match or whose content is not a
+// video is deleted; one that duplicates a video of the user is completed with
+// that video and fails with a *DuplicateError.
+func (u *Uploads) Complete(upload *models.Upload) (*models.Video, error) {
+	if !u.lock(upload.ID) {
+		return nil, ErrUploadBusy
+	}
+	defer u.unlock(upload.ID)
+
+	if err := u.db.First(upload, upload.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load upload: %w", err)
+	}
+	if upload.Status != models.UploadStatusUploading {
+		return nil, ErrUploadClosed
+	}
+	if upload.Offset != upload.Size {
+		return nil, ErrUploadIncomplete
+	}
+
+	if upload.Checksum != "" {
+		sum, err := fileSHA256(upload.StagingPath)
+		if err != nil {
+			return nil, err
+		}
+		if sum != upload.Checksum {
+			u.remove(upload)
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	filePath, err := u.ingester.StoragePath(upload.UserID, upload.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := moveFile(upload.StagingPath, filePath); err != nil {
+		return nil, err
+	}
+
+	videoRecord, err := u.ingester.Ingest(upload.UserID, upload.Filename, filePath, upload.Size)
+	if err != nil {
+		var duplicate *DuplicateError
+		if errors.As(err, &duplicate) {
+			// The upload is done; it is the video the user already has
+			os.Remove(filePath)
+			u.db.Model(upload).Updates(map[string]interface{}{
+				"status":       models.UploadStatusCompleted,
+				"video_id":     duplicate.Video.ID,
+				"staging_path": "",
+			})
+			return nil, err
+		}
+		if errors.Is(err, ErrNotVideo) || errors.Is(err, video.ErrInvalidVideo) {
+			// Retrying cannot help, so the upload is discarded
+			os.Remove(filePath)
+			u.remove(upload)
+			return nil, err
+		}
+		// Put the file back so completing can be retried
+		if moveErr := moveFile(filePath, upload.StagingPath); moveErr != nil {
+			log.Printf("Warning: failed to restore staging file of upload %d: %v", upload.ID, moveErr)
+		}
+		return nil, err
+	}
+
+	u.db.Model(upload).Updates(map[string]interface{}{
+		"status":       models.UploadStatusCompleted,
+		"video_id":     videoRecord.ID,
+		"staging_path": "",
+	})
+	return videoRecord, nil
+}
+
+// Abort deletes an unfinished upload and its data.
+func (u *Uploads) Abort(upload *models.Upload) error {
+	if !u.lock(upload.ID) {
+		return ErrUploadBusy
+	}
+	defer u.unlock(upload.ID)
+
+	return u.remove(upload)
+}
+
+func (u *Uploads) remove(upload *models.Upload) error {
+	if upload.StagingPath != "" {
+		os.Remove(upload.StagingPath)
+	}
+	if err := u.db.Delete(upload).Error; err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// Start deletes expired unfinished uploads in the background.
+func (u *Uploads) Start() {
+	go func() {
+		for {
+			u.sweep()
+			time.Sleep(sweepInterval)
+		}
+	}()
+}
+
+func (u *Uploads) sweep() {
+	var expired []models.Upload
+	if err := u.db.Where("status = ? AND expires_at < ?", models.UploadStatusUploading, time.Now()).
+		Find(&expired).Error; err != nil {
+		log.Printf("Warning: failed to load expired uploads: %v", err)
+		return
+	}
+
+	for i := range expired {
+		if !u.lock(expired[i].ID) {
+			continue
+		}
+		if err := u.remove(&expired[i]); err != nil {
+			log.Printf("Warning: failed to delete expired upload %d: %v", expired[i].ID, err)
+		}
+		u.unlock(expired[i].ID)
+	}
+}
+
+func (u *Uploads) lock(id uint) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.busy[id] {
+		return false
+	}
+	u.busy[id] = true
+	return true
+}
+
+func (u *Uploads) unlock(id uint) {
+	u.mu.Lock()
+	delete(u.busy, id)
+	u.mu.Unlock()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,266 @@
+package ingest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"io"
+	"opinion-monitor/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestUpload returns Uploads on a fake database and a declared upload of
+// size bytes with an empty staging file. Chunks are limited to 8 bytes.
+func newTestUpload(t *testing.T, size int64, checksum string) (*Uploads, *fakeUploadsDB, *models.Upload) {
+	t.Helper()
+	fake, db := newFakeUploadsDB(t)
+	stagingDir := t.TempDir()
+	u := &Uploads{
+		db:           db,
+		ingester:     &Ingester{db: db, uploadPath: t.TempDir()},
+		stagingDir:   stagingDir,
+		maxChunkSize: 8,
+		expiry:       time.Hour,
+		busy:         make(map[uint]bool),
+	}
+
+	stagingPath := filepath.Join(stagingDir, "1_1.part")
+	writeFile(t, stagingPath, "")
+	now := time.Now()
+	fake.insert(map[string]driver.Value{
+		"id": int64(1), "user_id": int64(1), "filename": "clip.mp4", "size": size, "upload_offset": int64(0),
+		"checksum": checksum, "staging_path": stagingPath, "status": string(models.UploadStatusUploading),
+		"video_id": nil, "expires_at": now.Add(time.Hour), "created_at": now, "updated_at": now,
+	})
+	return u, fake, &models.Upload{ID: 1}
+}
+
+func staged(t *testing.T, upload *models.Upload) string {
+	t.Helper()
+	data, err := os.ReadFile(upload.StagingPath)
+	if err != nil {
+		t.Fatalf("failed to read staging file: %v", err)
+	}
+	return string(data)
+}
+
+func storedOffset(fake *fakeUploadsDB) int64 {
+	return fake.row(1)["upload_offset"].(int64)
+}
+
+func sum(data string) []byte {
+	s := sha256.Sum256([]byte(data))
+	return s[:]
+}
+
+// brokenReader returns data, then fails as a dropped connection would.
+type brokenReader struct {
+	data io.Reader
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset by peer")
+	}
+	return n, err
+}
+
+func TestAppend(t *testing.T) {
+	u, fake, upload := newTestUpload(t, 12, "")
+
+	offset, err := u.Append(upload, 0, strings.NewReader("abcd"), sum("abcd"))
+	if err != nil || offset != 4 {
+		t.Fatalf("Append() = %d, %v, want 4", offset, err)
+	}
+	offset, err = u.Append(upload, 4, strings.NewReader("efgh"), nil)
+	if err != nil || offset != 8 {
+		t.Fatalf("Append() = %d, %v, want 8", offset, err)
+	}
+	if got := staged(t, upload); got != "abcdefgh" {
+		t.Fatalf("staging file = %q", got)
+	}
+	if got := storedOffset(fake); got != 8 {
+		t.Fatalf("stored offset = %d, want 8", got)
+	}
+}
+
+func TestAppendOffsetMismatch(t *testing.T) {
+	u, fake, upload := newTestUpload(t, 12, "")
+	if _, err := u.Append(upload, 0, strings.NewReader("abcd"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A retry of the first chunk, and a chunk from the future
+	for _, offset := range []int64{0, 6} {
+		got, err := u.Append(upload, offset, strings.NewReader("wxyz"), nil)
+		if !errors.Is(err, ErrOffsetMismatch) || got != 4 {
+			t.Fatalf("Append(offset %d) = %d, %v, want 4, %v", offset, got, err, ErrOffsetMismatch)
+		}
+	}
+	if got := staged(t, upload); got != "abcd" {
+		t.Fatalf("staging file = %q, want it unchanged", got)
+	}
+	if got := storedOffset(fake); got != 4 {
+		t.Fatalf("stored offset = %d, want 4", got)
+	}
+}
+
+func TestAppendDiscardsOversizedChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string // appended before the oversized chunk
+		chunk string
+	}{
+		{"over the maximum chunk size", "", "123456789"},
+		{"over the remaining length", "abcdefgh", "12345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, fake, upload := newTestUpload(t, 12, "")
+			if tt.first != "" {
+				if _, err := u.Append(upload, 0, strings.NewReader(tt.first), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			offset := int64(len(tt.first))
+
+			got, err := u.Append(upload, offset, strings.NewReader(tt.chunk), nil)
+			if !errors.Is(err, ErrChunkTooLarge) || got != offset {
+				t.Fatalf("Append() = %d, %v, want %d, %v", got, err, offset, ErrChunkTooLarge)
+			}
+			if got := staged(t, upload); got != tt.first {
+				t.Fatalf("staging file = %q, want %q", got, tt.first)
+			}
+			if got := storedOffset(fake); got != offset {
+				t.Fatalf("stored offset = %d, want %d", got, offset)
+			}
+		})
+	}
+}
+
+func TestAppendInterrupted(t *testing.T) {
+	t.Run("without a checksum the partial bytes are kept", func(t *testing.T) {
+		u, fake, upload := newTestUpload(t, 12, "")
+
+		got, err := u.Append(upload, 0, &brokenReader{strings.NewReader("abc")}, nil)
+		if !errors.Is(err, ErrUploadInterrupted) || got != 3 {
+			t.Fatalf("Append() = %d, %v, want 3, %v", got, err, ErrUploadInterrupted)
+		}
+		if got := staged(t, upload); got != "abc" {
+			t.Fatalf("staging file = %q, want the bytes received", got)
+		}
+		if got := storedOffset(fake); got != 3 {
+			t.Fatalf("stored offset = %d, want 3", got)
+		}
+
+		// The client resumes from the kept bytes
+		if got, err := u.Append(upload, 3, strings.NewReader("def"), nil); err != nil || got != 6 {
+			t.Fatalf("resumed Append() = %d, %v, want 6", got, err)
+		}
+	})
+
+	t.Run("with a checksum the chunk is discarded", func(t *testing.T) {
+		u, fake, upload := newTestUpload(t, 12, "")
+
+		got, err := u.Append(upload, 0, &brokenReader{strings.NewReader("abc")}, sum("abcd"))
+		if !errors.Is(err, ErrUploadInterrupted) || got != 0 {
+			t.Fatalf("Append() = %d, %v, want 0, %v", got, err, ErrUploadInterrupted)
+		}
+		if got := staged(t, upload); got != "" {
+			t.Fatalf("staging file = %q, want it empty", got)
+		}
+		if got := storedOffset(fake); got != 0 {
+			t.Fatalf("stored offset = %d, want 0", got)
+		}
+	})
+}
+
+func TestAppendChecksumMismatch(t *testing.T) {
+	u, fake, upload := newTestUpload(t, 12, "")
+	if _, err := u.Append(upload, 0, strings.NewReader("abcd"), sum("abcd")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := u.Append(upload, 4, strings.NewReader("efgh"), sum("efgX"))
+	if !errors.Is(err, ErrChecksumMismatch) || got != 4 {
+		t.Fatalf("Append() = %d, %v, want 4, %v", got, err, ErrChecksumMismatch)
+	}
+	if got := staged(t, upload); got != "abcd" {
+		t.Fatalf("staging file = %q, want the chunk rolled back", got)
+	}
+	if got := storedOffset(fake); got != 4 {
+		t.Fatalf("stored offset = %d, want 4", got)
+	}
+}
+
+func TestAppendToCompletedUpload(t *testing.T) {
+	u, fake, upload := newTestUpload(t, 4, "")
+	fake.set(1, "status", string(models.UploadStatusCompleted))
+
+	if _, err := u.Append(upload, 0, strings.NewReader("abcd"), nil); !errors.Is(err, ErrUploadClosed) {
+		t.Fatalf("Append() error = %v, want %v", err, ErrUploadClosed)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	t.Run("incomplete upload", func(t *testing.T) {
+		u, fake, upload := newTestUpload(t, 8, "")
+		if _, err := u.Append(upload, 0, strings.NewReader("abcd"), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := u.Complete(upload); !errors.Is(err, ErrUploadIncomplete) {
+			t.Fatalf("Complete() error = %v, want %v", err, ErrUploadIncomplete)
+		}
+		if fake.row(1) == nil {
+			t.Fatalf("incomplete upload was deleted")
+		}
+	})
+
+	t.Run("wrong whole-file checksum deletes the upload", func(t *testing.T) {
+		declared := hex.EncodeToString(sum("abcdefgh"))
+		u, fake, upload := newTestUpload(t, 8, declared)
+		for _, chunk := range []string{"abcd", "efgX"} {
+			if _, err := u.Append(upload, upload.Offset, strings.NewReader(chunk), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := u.Complete(upload); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Complete() error = %v, want %v", err, ErrChecksumMismatch)
+		}
+		if fake.row(1) != nil {
+			t.Fatalf("upload with a wrong checksum was kept")
+		}
+		if _, err := os.Stat(upload.StagingPath); !os.IsNotExist(err) {
+			t.Fatalf("staging file was kept: %v", err)
+		}
+	})
+
+	t.Run("content that is not a video deletes the upload", func(t *testing.T) {
+		content := "<html>not a video</html>"
+		u, fake, upload := newTestUpload(t, int64(len(content)), hex.EncodeToString(sum(content)))
+		u.maxChunkSize = 64
+		if _, err := u.Append(upload, 0, bytes.NewReader([]byte(content)), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := u.Complete(upload); !errors.Is(err, ErrNotVideo) {
+			t.Fatalf("Complete() error = %v, want %v", err, ErrNotVideo)
+		}
+		if fake.row(1) != nil {
+			t.Fatalf("upload that is not a video was kept")
+		}
+		if files := storedFiles(t, u.ingester.uploadPath); len(files) > 0 {
+			t.Fatalf("Complete() left %v in storage", files)
+		}
+	})
+}
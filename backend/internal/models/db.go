@@ -26,5 +26,17 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&User{}, &Video{}, &Report{}, &Job{})
+	if err := db.AutoMigrate(&User{}, &Video{}, &Report{}, &ReportFrame{}, &TranscriptSegment{}, &Job{}, &JobAttempt{}, &JobStage{}, &Webhook{}, &WebhookDelivery{}, &Watchlist{}, &WatchlistTerm{}, &AlertRule{}, &Alert{}, &NotificationChannel{}, &Notification{}, &NotificationDelivery{}, &DigestSchedule{}, &Digest{}, &Upload{}, &VideoFingerprint{}, &FingerprintBand{}); err != nil {
+		return err
+	}
+
+	// reports.video_id used to be unique; versioned reports replace that
+	// index with idx_reports_video_version, which AutoMigrate does not drop.
+	if db.Migrator().HasIndex(&Report{}, "idx_reports_video_id") {
+		if err := db.Migrator().DropIndex(&Report{}, "idx_reports_video_id"); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
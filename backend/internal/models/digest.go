@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Digest periods.
+const (
+	DigestPeriodDaily  = "daily"
+	DigestPeriodWeekly = "weekly"
+)
+
+// Digest scopes.
+const (
+	DigestScopeUser   = "user"   // the owner's videos
+	DigestScopeTenant = "tenant" // the videos of every user in the owner's tenant
+)
+
+// DigestSchedule makes a digest of the reports of a period at the end of
+// each period and sends it to its owner.
+type DigestSchedule struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
+	Period    string         `gorm:"type:varchar(20);not null" json:"period"` // daily or weekly
+	Scope     string         `gorm:"type:varchar(20);not null;default:'user'" json:"scope"`
+	Tenant    string         `gorm:"type:varchar(50)" json:"tenant,omitempty"` // set for the tenant scope
+	Channels  string         `gorm:"type:varchar(100)" json:"channels"`        // comma separated, see AlertChannels
+	Summary   bool           `gorm:"default:false" json:"summary"`             // ask the chat model for an executive summary
+	Active    bool           `gorm:"default:true" json:"active"`
+	NextRunAt *time.Time     `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt *time.Time     `json:"last_run_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Notifies reports whether digests should be sent to the given channel.
+func (s *DigestSchedule) Notifies(channel string) bool {
+	return listContains(s.Channels, channel)
+}
+
+// Digest is the summary of the reports created in [PeriodStart, PeriodEnd).
+type Digest struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	ScheduleID   uint      `gorm:"not null;index" json:"schedule_id"`
+	Period       string    `gorm:"type:varchar(20);not null" json:"period"`
+	Scope        string    `gorm:"type:varchar(20);not null" json:"scope"`
+	PeriodStart  time.Time `gorm:"index" json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	ReportCount  int       `json:"report_count"`
+	Stats        string    `gorm:"type:longtext" json:"stats"` // JSON DigestStats
+	Summary      string    `gorm:"type:text" json:"summary"`   // executive summary written by the chat model
+	SummaryError string    `gorm:"type:text" json:"summary_error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DigestStats are the figures of a digest, stored as JSON in Digest.Stats.
+type DigestStats struct {
+	Total        int            `json:"total"`
+	BySentiment  map[string]int `json:"by_sentiment"` // positive, neutral, negative
+	ByRisk       map[string]int `json:"by_risk"`      // low, medium, high
+	AverageScore float64        `json:"average_score"`
+	TopTopics    []TopicCount   `json:"top_topics"`
+	HighestRisk  []VideoRisk    `json:"highest_risk"`
+	Trend        DigestTrend    `json:"trend"`
+}
+
+// TopicCount is how many reports of the period name a key topic.
+type TopicCount struct {
+	Topic         string `json:"topic"`
+	Count         int    `json:"count"`
+	PreviousCount int    `json:"previous_count"`
+}
+
+// VideoRisk is one of the riskiest videos of the period.
+type VideoRisk struct {
+	VideoID          uint    `json:"video_id"`
+	ReportID         uint    `json:"report_id"`
+	OriginalFilename string  `json:"original_filename"`
+	RiskLevel        string  `json:"risk_level"`
+	SentimentScore   float64 `json:"sentiment_score"`
+	SentimentLabel   string  `json:"sentiment_label"`
+}
+
+// DigestTrend compares the period with the one of the same length before it.
+type DigestTrend struct {
+	PreviousStart        time.Time `json:"previous_start"`
+	PreviousEnd          time.Time `json:"previous_end"`
+	PreviousTotal        int       `json:"previous_total"`
+	TotalChange          int       `json:"total_change"`
+	PreviousAverageScore float64   `json:"previous_average_score"`
+	AverageScoreChange   float64   `json:"average_score_change"`
+	PreviousNegative     int       `json:"previous_negative"`
+	NegativeChange       int       `json:"negative_change"`
+	PreviousHighRisk     int       `json:"previous_high_risk"`
+	HighRiskChange       int       `json:"high_risk_change"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// VideoFingerprint holds the perceptual hashes of frames sampled evenly
+// across a video, used to find re-uploads and edited copies of it.
+type VideoFingerprint struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	VideoID   uint      `gorm:"uniqueIndex;not null" json:"video_id"`
+	Hashes    string    `gorm:"type:text" json:"hashes"` // 16-digit hex frame hashes, comma separated, in time order
+	Frames    int       `json:"frames"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FingerprintBand indexes one band of a frame hash. Hashes are cut into one
+// more band than the bits two matching hashes may differ in, so matching
+// hashes share at least one band and looking up the bands of a video finds
+// its candidate near-duplicates without comparing every video.
+type FingerprintBand struct {
+	ID      uint  `gorm:"primarykey"`
+	VideoID uint  `gorm:"not null;index"`
+	Band    int64 `gorm:"not null;index"` // band count << 24 | band number << 16 | band value
+}
@@ -13,17 +13,39 @@ const (
 	JobStatusProcessing JobStatus = "processing"
 	JobStatusCompleted  JobStatus = "completed"
 	JobStatusFailed     JobStatus = "failed"
+	JobStatusDead       JobStatus = "dead" // retries exhausted or fatal error; requeue manually
 )
 
 type Job struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	VideoID      uint           `gorm:"uniqueIndex;not null" json:"video_id"`
-	Status       JobStatus      `gorm:"type:varchar(20);default:'pending';index" json:"status"`
-	RetryCount   int            `gorm:"default:0" json:"retry_count"`
-	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	VideoID        uint           `gorm:"uniqueIndex;not null" json:"video_id"`
+	Status         JobStatus      `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	RetryCount     int            `gorm:"default:0" json:"retry_count"`
+	ErrorMessage   string         `gorm:"type:text" json:"error_message,omitempty"`
+	CurrentStage   string         `gorm:"type:varchar(50)" json:"current_stage,omitempty"`
+	ModelVision    string         `gorm:"type:varchar(100)" json:"model_vision,omitempty"`  // overrides openai.model_vision for this job
+	ModelChat      string         `gorm:"type:varchar(100)" json:"model_chat,omitempty"`    // overrides openai.model_chat for this job
+	PromptVersion  string         `gorm:"type:varchar(50)" json:"prompt_version,omitempty"` // overrides prompts.default_version for this job
+	LeaseOwner     string         `gorm:"type:varchar(100)" json:"-"`                       // worker currently holding the job
+	LeaseExpiresAt *time.Time     `gorm:"index" json:"lease_expires_at,omitempty"`          // job is reclaimable after this time
+	AvailableAt    *time.Time     `gorm:"index" json:"available_at,omitempty"`              // pending job is not claimed before this time
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Video Video `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+	Video    Video        `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+	Attempts []JobAttempt `gorm:"foreignKey:JobID" json:"attempts,omitempty"`
+	Stages   []JobStage   `gorm:"foreignKey:JobID" json:"stages,omitempty"`
+}
+
+// JobAttempt records the outcome of a single run of a job.
+type JobAttempt struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	JobID      uint      `gorm:"not null;index" json:"job_id"`
+	Attempt    int       `json:"attempt"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
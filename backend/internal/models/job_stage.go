@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+type StageStatus string
+
+const (
+	StageStatusPending   StageStatus = "pending"
+	StageStatusRunning   StageStatus = "running"
+	StageStatusCompleted StageStatus = "completed"
+	StageStatusSkipped   StageStatus = "skipped" // optional stage failed or was not applicable
+	StageStatusFailed    StageStatus = "failed"
+)
+
+// JobStage is the checkpoint of one pipeline stage of a job. Completed
+// stages are not rerun when a job is retried; their Output is loaded back
+// into the pipeline instead. Skipped stages are rerun until the analysis is
+// completed.
+type JobStage struct {
+	ID         uint        `gorm:"primarykey" json:"id"`
+	JobID      uint        `gorm:"not null;uniqueIndex:idx_job_stage" json:"job_id"`
+	Name       string      `gorm:"type:varchar(50);not null;uniqueIndex:idx_job_stage" json:"name"`
+	Position   int         `json:"position"`
+	Status     StageStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	Output     string      `gorm:"type:longtext" json:"output,omitempty"` // JSON encoded stage result
+	Error      string      `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Duration   float64     `json:"duration"` // in seconds
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
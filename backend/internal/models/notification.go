@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification channel types.
+const (
+	ChannelTypeEmail    = "email"
+	ChannelTypeFeishu   = "feishu"
+	ChannelTypeDingTalk = "dingtalk"
+	ChannelTypeWeCom    = "wecom"
+	ChannelTypeSlack    = "slack"
+)
+
+// ChatChannelTypes are the incoming-webhook chat channel types.
+var ChatChannelTypes = []string{ChannelTypeFeishu, ChannelTypeDingTalk, ChannelTypeWeCom, ChannelTypeSlack}
+
+// NotificationChannel is a destination a user receives notifications at: an
+// email address or a chat group's incoming webhook.
+type NotificationChannel struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	Type      string         `gorm:"type:varchar(20);not null" json:"type"`
+	Name      string         `gorm:"type:varchar(100)" json:"name"`
+	Target    string         `gorm:"type:varchar(500);not null" json:"target"` // email address or webhook URL
+	Secret    string         `gorm:"type:varchar(255)" json:"-"`               // signing secret of Feishu and DingTalk robots
+	Active    bool           `gorm:"default:true" json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Notification is a message in a user's in-app inbox.
+type Notification struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	AlertID   *uint      `gorm:"index" json:"alert_id,omitempty"`
+	Title     string     `gorm:"type:varchar(255)" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	Link      string     `gorm:"type:varchar(500)" json:"link,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationDelivery is one message sent to one email address or chat
+// webhook, with the outcome of its latest attempt.
+type NotificationDelivery struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	UserID        uint           `gorm:"not null;index" json:"user_id"`
+	ChannelID     *uint          `gorm:"index" json:"channel_id,omitempty"` // empty for the account email
+	ChannelType   string         `gorm:"type:varchar(20);not null" json:"channel_type"`
+	Target        string         `gorm:"type:varchar(500);not null" json:"target"`
+	AlertID       *uint          `gorm:"index" json:"alert_id,omitempty"`
+	Title         string         `gorm:"type:varchar(255)" json:"title"`
+	Body          string         `gorm:"type:text" json:"body"`
+	Link          string         `gorm:"type:varchar(500)" json:"link,omitempty"`
+	Status        DeliveryStatus `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Attempts      int            `gorm:"default:0" json:"attempts"`
+	Error         string         `gorm:"type:text" json:"error,omitempty"`
+	NextAttemptAt *time.Time     `gorm:"index" json:"next_attempt_at,omitempty"`
+	SentAt        *time.Time     `json:"sent_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
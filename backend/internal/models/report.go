@@ -8,7 +8,13 @@ import (
 
 type Report struct {
 	ID               uint           `gorm:"primarykey" json:"id"`
-	VideoID          uint           `gorm:"uniqueIndex;not null" json:"video_id"`
+	VideoID          uint           `gorm:"uniqueIndex:idx_reports_video_version;not null" json:"video_id"`
+	Version          int            `gorm:"uniqueIndex:idx_reports_video_version;default:1" json:"version"`
+	IsCurrent        bool           `gorm:"default:true;index" json:"is_current"` // the version shown for the video
+	ModelVision      string         `gorm:"type:varchar(100)" json:"model_vision"`
+	ModelChat        string         `gorm:"type:varchar(100)" json:"model_chat"`
+	PromptVersion    string         `gorm:"type:varchar(50)" json:"prompt_version"`
+	PipelineVersion  string         `gorm:"type:varchar(50)" json:"pipeline_version"`
 	CoverText        string         `gorm:"type:text" json:"cover_text"`
 	TranscriptText   string         `gorm:"type:text" json:"transcript_text"`
 	SentimentScore   float64        `json:"sentiment_score"`
@@ -17,9 +23,22 @@ type Report struct {
 	RiskLevel        string         `gorm:"type:varchar(20)" json:"risk_level"`
 	DetailedAnalysis string         `gorm:"type:text" json:"detailed_analysis"`
 	Recommendations  string         `gorm:"type:text" json:"recommendations"` // JSON array stored as string
+	Evidence         string         `gorm:"type:longtext" json:"evidence"`    // JSON array of per-chunk findings, empty unless analyzed in chunks
 	ProcessingTime   float64        `json:"processing_time"`                  // in seconds
 	CreatedAt        time.Time      `json:"created_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Video Video `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+	Video  Video         `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+	Frames []ReportFrame `gorm:"foreignKey:ReportID" json:"frames,omitempty"`
+}
+
+// ReportFrame is the text recognized on one sampled frame of the video.
+type ReportFrame struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ReportID  uint      `gorm:"not null;index" json:"report_id"`
+	Timestamp float64   `json:"timestamp"` // in seconds
+	ImagePath string    `gorm:"type:varchar(500)" json:"image_path"`
+	Text      string    `gorm:"type:text" json:"text"`
+	Duplicate bool      `json:"duplicate"` // repeats the text of an earlier frame
+	CreatedAt time.Time `json:"created_at"`
 }
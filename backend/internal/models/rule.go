@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Channels alerts can be sent to.
+const (
+	ChannelWebhook = "webhook" // the owner's webhooks subscribed to alert.triggered (digest.created for digests)
+	ChannelInbox   = "inbox"   // the owner's in-app inbox
+	ChannelEmail   = "email"   // the owner's email channels, or the account email if there are none
+	ChannelChat    = "chat"    // the owner's Feishu, DingTalk, WeCom and Slack channels
+)
+
+// AlertChannels lists the channels alerts can be sent to.
+var AlertChannels = []string{ChannelWebhook, ChannelInbox, ChannelEmail, ChannelChat}
+
+// AlertRule raises an alert on every new report of the owner's videos that
+// satisfies its condition.
+type AlertRule struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Condition   string         `gorm:"type:text;not null" json:"condition"`               // JSON condition tree, see package rules
+	Severity    string         `gorm:"type:varchar(20);default:'medium'" json:"severity"` // low, medium or high
+	Channels    string         `gorm:"type:varchar(255)" json:"channels"`                 // comma separated channel names
+	Active      bool           `gorm:"default:true" json:"active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Notifies reports whether the rule sends its alerts to channel.
+func (r *AlertRule) Notifies(channel string) bool {
+	return listContains(r.Channels, channel)
+}
+
+// listContains reports whether a comma separated list contains item.
+func listContains(list, item string) bool {
+	for _, e := range strings.Split(list, ",") {
+		if strings.TrimSpace(e) == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// TranscriptSegment is a timestamped piece of a video's audio transcript.
+type TranscriptSegment struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	VideoID   uint      `gorm:"not null;index" json:"video_id"`
+	Position  int       `json:"position"`
+	Start     float64   `json:"start"` // in seconds
+	End       float64   `json:"end"`   // in seconds
+	Text      string    `gorm:"type:text" json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
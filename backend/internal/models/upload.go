@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type UploadStatus string
+
+const (
+	UploadStatusUploading UploadStatus = "uploading"
+	UploadStatusCompleted UploadStatus = "completed" // the video was created
+)
+
+// Upload is a resumable upload of one video file. Its data is staged on disk
+// and appended chunk by chunk; the video is only created once every byte has
+// arrived and the checksum matches.
+type Upload struct {
+	ID          uint         `gorm:"primarykey" json:"id"`
+	UserID      uint         `gorm:"not null;index" json:"user_id"`
+	Filename    string       `gorm:"type:varchar(255);not null" json:"filename"`
+	Size        int64        `gorm:"not null" json:"size"`                         // total length in bytes
+	Offset      int64        `gorm:"column:upload_offset;default:0" json:"offset"` // bytes received so far
+	Checksum    string       `gorm:"type:varchar(64)" json:"checksum,omitempty"`   // expected SHA-256 of the whole file, hex
+	StagingPath string       `gorm:"type:varchar(500)" json:"-"`
+	Status      UploadStatus `gorm:"type:varchar(20);default:'uploading';index" json:"status"`
+	VideoID     *uint        `json:"video_id,omitempty"`
+	ExpiresAt   time.Time    `gorm:"index" json:"expires_at"` // unfinished uploads are deleted after this
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
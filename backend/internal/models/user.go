@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"opinion-monitor/internal/config"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,9 +13,34 @@ type User struct {
 	Username     string         `gorm:"type:varchar(50);uniqueIndex;not null" json:"username"`
 	Email        string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
 	PasswordHash string         `gorm:"type:varchar(255);not null" json:"-"`
+	Tenant       string         `gorm:"type:varchar(50);index" json:"tenant,omitempty"` // assigned from the tenants config, see AssignTenants
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Videos []Video `gorm:"foreignKey:UserID" json:"videos,omitempty"`
 }
+
+// AssignTenants sets the tenant of every user from the tenants config; users
+// not listed get none. Without a tenants config nothing is changed, so
+// tenants set in the database directly are kept.
+func AssignTenants(db *gorm.DB, tenants []config.TenantConfig) error {
+	if len(tenants) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("tenant <> ?", "").Update("tenant", "").Error; err != nil {
+			return fmt.Errorf("failed to clear tenants: %w", err)
+		}
+		for _, tenant := range tenants {
+			if len(tenant.Users) == 0 {
+				continue
+			}
+			if err := tx.Model(&User{}).Where("username IN ?", tenant.Users).Update("tenant", tenant.Name).Error; err != nil {
+				return fmt.Errorf("failed to assign tenant %s: %w", tenant.Name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -16,21 +16,26 @@ const (
 )
 
 type Video struct {
-	ID               uint           `gorm:"primarykey" json:"id"`
-	UserID           uint           `gorm:"not null;index" json:"user_id"`
-	OriginalFilename string         `gorm:"type:varchar(255);not null" json:"original_filename"`
-	FilePath         string         `gorm:"type:varchar(500);not null" json:"file_path"`
-	CoverPath        string         `gorm:"type:varchar(500)" json:"cover_path"`
-	AudioPath        string         `gorm:"type:varchar(500)" json:"audio_path"`
-	TranscriptText   string         `gorm:"type:text" json:"transcript_text"`
-	FileSize         int64          `json:"file_size"`
-	Duration         float64        `json:"duration"`
-	Status           VideoStatus    `gorm:"type:varchar(20);default:'pending';index" json:"status"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
-	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	UserID             uint           `gorm:"not null;index" json:"user_id"`
+	OriginalFilename   string         `gorm:"type:varchar(255);not null" json:"original_filename"`
+	FilePath           string         `gorm:"type:varchar(500);not null" json:"file_path"`
+	CoverPath          string         `gorm:"type:varchar(500)" json:"cover_path"`
+	AudioPath          string         `gorm:"type:varchar(500)" json:"audio_path"`
+	TranscriptText     string         `gorm:"type:text" json:"transcript_text"`
+	TranscriptLanguage string         `gorm:"type:varchar(20)" json:"transcript_language"`
+	FileSize           int64          `json:"file_size"`
+	Duration           float64        `json:"duration"`
+	Status             VideoStatus    `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	ContentHash        string         `gorm:"type:char(64);index" json:"content_hash,omitempty"` // SHA-256 of the file
+	DuplicateOfID      *uint          `gorm:"index" json:"duplicate_of_id,omitempty"`            // video whose analysis was reused
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 
 	User   User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Report *Report `gorm:"foreignKey:VideoID" json:"report,omitempty"`
+	Report *Report `gorm:"foreignKey:VideoID" json:"report,omitempty"` // preload with is_current = true
 	Job    *Job    `gorm:"foreignKey:VideoID" json:"job,omitempty"`
+
+	TranscriptSegments []TranscriptSegment `gorm:"foreignKey:VideoID" json:"transcript_segments,omitempty"`
 }
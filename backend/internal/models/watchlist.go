@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// How a watchlist term is matched against text.
+const (
+	MatchTypeContains = "contains" // case-insensitive substring, works for any script
+	MatchTypeWord     = "word"     // case-insensitive whole word, for space-delimited scripts
+	MatchTypeRegex    = "regex"    // RE2 regular expression
+)
+
+// Watchlist is a named set of keywords or entities to look for in analyzed
+// videos. A shared watchlist applies to the videos of every user in the
+// owner's tenant; otherwise it only applies to the owner's videos.
+type Watchlist struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	Tenant      string         `gorm:"type:varchar(50);index" json:"tenant,omitempty"` // owner's tenant, set when shared
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Shared      bool           `gorm:"default:false" json:"shared"`
+	Channels    string         `gorm:"type:varchar(255)" json:"channels"` // comma separated, alerts are sent to the owner
+	Active      bool           `gorm:"default:true" json:"active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Terms []WatchlistTerm `gorm:"foreignKey:WatchlistID" json:"terms,omitempty"`
+}
+
+// Notifies reports whether alerts raised by the watchlist are sent to channel.
+func (w *Watchlist) Notifies(channel string) bool {
+	return listContains(w.Channels, channel)
+}
+
+// WatchlistTerm is a keyword, name or pattern on a watchlist.
+type WatchlistTerm struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	WatchlistID   uint      `gorm:"not null;index" json:"watchlist_id"`
+	Term          string    `gorm:"type:varchar(255);not null" json:"term"`
+	Category      string    `gorm:"type:varchar(50)" json:"category,omitempty"` // free-form, e.g. brand, person, sensitive
+	MatchType     string    `gorm:"type:varchar(20);default:'contains'" json:"match_type"`
+	CaseSensitive bool      `gorm:"default:false" json:"case_sensitive"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AlertStatus string
+
+const (
+	AlertStatusOpen         AlertStatus = "open"
+	AlertStatusAcknowledged AlertStatus = "acknowledged"
+	AlertStatusResolved     AlertStatus = "resolved"
+)
+
+// What raised an alert.
+const (
+	AlertSourceWatchlist = "watchlist"
+	AlertSourceRule      = "rule"
+)
+
+// Where in an analysis a watchlist term was found.
+const (
+	AlertLocationCoverText  = "cover_text"
+	AlertLocationTranscript = "transcript"
+	AlertLocationKeyTopics  = "key_topics"
+)
+
+// Alert records a watchlist term found in a report, or a report satisfying
+// an alert rule. For terms, only the first occurrence per location is
+// quoted and Occurrences counts them all; for rules, Snippet lists the
+// comparisons that matched.
+type Alert struct {
+	ID             uint        `gorm:"primarykey" json:"id"`
+	UserID         uint        `gorm:"not null;index" json:"user_id"` // owner of the video
+	VideoID        uint        `gorm:"not null;index" json:"video_id"`
+	ReportID       uint        `gorm:"not null;index" json:"report_id"`
+	Source         string      `gorm:"type:varchar(20);default:'watchlist';index" json:"source"`
+	WatchlistID    *uint       `gorm:"index" json:"watchlist_id,omitempty"`
+	TermID         uint        `json:"term_id,omitempty"`
+	RuleID         *uint       `gorm:"index" json:"rule_id,omitempty"`
+	Term           string      `gorm:"type:varchar(255)" json:"term,omitempty"`
+	Category       string      `gorm:"type:varchar(50)" json:"category,omitempty"`
+	Severity       string      `gorm:"type:varchar(20)" json:"severity,omitempty"` // of the rule
+	Location       string      `gorm:"type:varchar(20)" json:"location,omitempty"`
+	Snippet        string      `gorm:"type:text" json:"snippet"`
+	Timestamp      *float64    `json:"timestamp,omitempty"` // seconds into the video, for frame text and transcript matches
+	Occurrences    int         `gorm:"default:1" json:"occurrences"`
+	Status         AlertStatus `gorm:"type:varchar(20);default:'open';index" json:"status"`
+	AcknowledgedBy *uint       `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time  `json:"acknowledged_at,omitempty"`
+	ResolvedBy     *uint       `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time  `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+
+	Watchlist *Watchlist `gorm:"foreignKey:WatchlistID" json:"watchlist,omitempty"`
+	Rule      *AlertRule `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
+	Video     *Video     `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook event types.
+const (
+	WebhookEventReportCompleted = "report.completed" // a new report version was saved
+	WebhookEventReportHighRisk  = "report.high_risk" // a new report has risk_level "high"
+	WebhookEventAlertTriggered  = "alert.triggered"  // an alert rule notifying the webhook channel matched
+	WebhookEventDigestCreated   = "digest.created"   // a digest schedule notifying the webhook channel ran
+	WebhookEventPing            = "ping"             // sent by the test endpoint only
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to.
+var WebhookEvents = []string{WebhookEventReportCompleted, WebhookEventReportHighRisk, WebhookEventAlertTriggered, WebhookEventDigestCreated}
+
+// Webhook is a user's subscription to events, delivered as signed HTTP POSTs.
+type Webhook struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	URL       string         `gorm:"type:varchar(500);not null" json:"url"`
+	Events    string         `gorm:"type:varchar(255);not null" json:"events"` // comma separated event types
+	Secret    string         `gorm:"type:varchar(100);not null" json:"-"`      // HMAC-SHA256 signing key
+	Active    bool           `gorm:"default:true" json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Subscribes reports whether the webhook wants events of the given type.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed" // attempts exhausted
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// latest attempt.
+type WebhookDelivery struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	WebhookID     uint           `gorm:"not null;index" json:"webhook_id"`
+	Event         string         `gorm:"type:varchar(50);not null" json:"event"`
+	Payload       string         `gorm:"type:longtext" json:"payload"`
+	Status        DeliveryStatus `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Attempts      int            `gorm:"default:0" json:"attempts"`
+	ResponseCode  int            `json:"response_code,omitempty"`
+	ResponseBody  string         `gorm:"type:text" json:"response_body,omitempty"` // truncated
+	Error         string         `gorm:"type:text" json:"error,omitempty"`
+	NextAttemptAt *time.Time     `gorm:"index" json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
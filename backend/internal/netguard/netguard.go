@@ -0,0 +1,154 @@
+// Package netguard builds HTTP clients for requests to URLs supplied by
+// users, such as webhooks, chat channels and video imports, so that they
+// cannot be aimed at the server's own network or cloud metadata endpoints.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxRedirects is how many hops a client made with followRedirects takes.
+const maxRedirects = 5
+
+var (
+	ErrPrivateAddress = errors.New("URL resolves to a private or loopback address")
+	ErrTooManyHops    = errors.New("too many redirects")
+)
+
+// specialRanges are not reachable on the public internet but are not covered
+// by the net.IP predicates (RFC 6890 and IANA special-purpose registries).
+var specialRanges = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",       // "this network"
+		"100.64.0.0/10",   // carrier-grade NAT
+		"192.0.0.0/24",    // IETF protocol assignments
+		"192.0.2.0/24",    // documentation
+		"198.18.0.0/15",   // benchmarking
+		"198.51.100.0/24", // documentation
+		"203.0.113.0/24",  // documentation
+		"240.0.0.0/4",     // reserved, including broadcast
+		"64:ff9b::/96",    // NAT64, which embeds IPv4 addresses
+		"64:ff9b:1::/48",  // local-use NAT64
+		"100::/64",        // discard-only
+		"2001:db8::/32",   // documentation
+		"2002::/16",       // 6to4, which can embed private IPv4 addresses
+	}
+	ranges := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		ranges = append(ranges, n)
+	}
+	return ranges
+}()
+
+// IsPrivateIP reports whether ip is loopback, private, link-local, multicast
+// or in another range that is not reachable on the public internet.
+func IsPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, n := range specialRanges {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns a client that refuses to connect to private addresses
+// unless allowPrivate is set. The check runs on the resolved address of
+// every connection, so DNS tricks and redirects cannot get around it, and
+// proxy environment variables are ignored. Redirects are returned to the
+// caller as is unless followRedirects is set.
+func NewClient(timeout time.Duration, allowPrivate, followRedirects bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsPrivateIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	client := &http.Client{Timeout: timeout, Transport: transport}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !followRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return ErrTooManyHops
+		}
+		return nil
+	}
+	return client
+}
+
+// CheckURL refuses a URL whose host resolves to a private address, so that
+// such targets are rejected when they are saved rather than on first use.
+// Hosts that do not resolve yet are accepted; the client checks again on
+// every connection.
+func CheckURL(raw string, allowPrivate bool) error {
+	if allowPrivate {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if IsPrivateIP(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+	if host == "localhost" {
+		return ErrPrivateAddress
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if IsPrivateIP(ip) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// Do sends req with client and also reports whether the response came from
+// a private address, in which case callers must not show its body to users.
+func Do(client *http.Client, req *http.Request) (*http.Response, bool, error) {
+	private := false
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok && IsPrivateIP(addr.IP) {
+				private = true
+			}
+		},
+	}
+
+	resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	return resp, private, err
+}
@@ -0,0 +1,79 @@
+package netguard
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // cloud metadata
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"192.0.0.8", true},
+		{"198.18.0.1", true},
+		{"203.0.113.5", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"::", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true}, // NAT64 of 169.254.169.254
+		{"64:ff9b::808:808", true},   // NAT64 is refused whatever it embeds
+		{"64:ff9b:1::1", true},
+		{"2002:7f00:1::", true}, // 6to4 of 127.0.0.1
+		{"2001:db8::1", true},
+		{"100::1", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"100.128.0.1", false}, // just past carrier-grade NAT
+		{"172.32.0.1", false},
+		{"2606:4700:4700::1111", false},
+		{"64:ff9c::1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("invalid test address %q", tt.ip)
+			}
+			if got := IsPrivateIP(ip); got != tt.want {
+				t.Fatalf("IsPrivateIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"https://8.8.8.8/hook", false, false},
+		{"http://127.0.0.1:8080/hook", false, true},
+		{"http://localhost/hook", false, true},
+		{"http://[64:ff9b::a9fe:a9fe]/latest/meta-data", false, true},
+		{"http://127.0.0.1:8080/hook", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := CheckURL(tt.url, tt.allowPrivate); (err != nil) != tt.wantErr {
+				t.Fatalf("CheckURL(%q, %v) error = %v, want error %v", tt.url, tt.allowPrivate, err, tt.wantErr)
+			}
+		})
+	}
+}
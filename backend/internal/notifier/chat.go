@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"opinion-monitor/internal/netguard"
+	"strconv"
+	"time"
+)
+
+// Chat robots take a plain-text message; the title goes on the first line
+// and the link on the last.
+func chatText(msg Message) string {
+	text := msg.Title + "\n" + msg.Body
+	if msg.Link != "" {
+		text += "\n" + msg.Link
+	}
+	return text
+}
+
+// feishuSender posts to a Feishu (Lark) custom bot webhook.
+type feishuSender struct {
+	httpClient *http.Client
+}
+
+func (s *feishuSender) Send(target, secret string, msg Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": chatText(msg)},
+	}
+	if secret != "" {
+		// Signature verification: the key is "<timestamp>\n<secret>", the message is empty
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return postChat(s.httpClient, target, payload)
+}
+
+// dingTalkSender posts to a DingTalk custom robot webhook.
+type dingTalkSender struct {
+	httpClient *http.Client
+}
+
+func (s *dingTalkSender) Send(target, secret string, msg Message) error {
+	if secret != "" {
+		// Signing: HMAC-SHA256 of "<timestamp ms>\n<secret>" keyed with the secret, as query parameters
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "\n" + secret))
+
+		u, err := url.Parse(target)
+		if err != nil {
+			return fmt.Errorf("invalid webhook url: %w", err)
+		}
+		query := u.Query()
+		query.Set("timestamp", timestamp)
+		query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		u.RawQuery = query.Encode()
+		target = u.String()
+	}
+
+	return postChat(s.httpClient, target, map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": chatText(msg)},
+	})
+}
+
+// weComSender posts to a WeCom (WeChat Work) group robot webhook.
+type weComSender struct {
+	httpClient *http.Client
+}
+
+func (s *weComSender) Send(target, _ string, msg Message) error {
+	return postChat(s.httpClient, target, map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": chatText(msg)},
+	})
+}
+
+// slackSender posts to a Slack incoming webhook, or anything accepting the
+// same {"text": ...} payload.
+type slackSender struct {
+	httpClient *http.Client
+}
+
+func (s *slackSender) Send(target, _ string, msg Message) error {
+	text := "*" + msg.Title + "*\n" + msg.Body
+	if msg.Link != "" {
+		text += "\n<" + msg.Link + ">"
+	}
+	return postChat(s.httpClient, target, map[string]interface{}{"text": text})
+}
+
+// chatResponse covers the error fields of the robots' JSON replies. Feishu
+// answers with code (or StatusCode on older versions), DingTalk and WeCom
+// with errcode; Slack answers "ok" in plain text.
+type chatResponse struct {
+	Code       *int   `json:"code"`
+	StatusCode *int   `json:"StatusCode"`
+	ErrCode    *int   `json:"errcode"`
+	Msg        string `json:"msg"`
+	ErrMsg     string `json:"errmsg"`
+}
+
+// postChat POSTs a JSON payload and checks both the HTTP status and the
+// error code in the reply, since robots report most errors with 200 OK.
+func postChat(client *http.Client, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, private, err := netguard.Do(client, req)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The reply itself is never put in the error, which channel owners see;
+	// only the robots' own error fields are, and not from internal hosts
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var reply chatResponse
+	if json.Unmarshal(respBody, &reply) != nil {
+		return nil
+	}
+	for _, code := range []*int{reply.Code, reply.StatusCode, reply.ErrCode} {
+		if code != nil && *code != 0 {
+			if private {
+				return fmt.Errorf("webhook returned error %d", *code)
+			}
+			return fmt.Errorf("webhook returned error %d: %s%s", *code, reply.Msg, reply.ErrMsg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package notifier sends messages to users through the in-app inbox, email
+// and chat-group incoming webhooks (Feishu, DingTalk, WeCom and Slack).
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"opinion-monitor/internal/backoff"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/netguard"
+	"opinion-monitor/internal/outbox"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = time.Minute
+	defaultMaxBackoff     = time.Hour
+	defaultTimeout        = 10 * time.Second
+	defaultPollInterval   = 5 * time.Second
+)
+
+// errNotConfigured is returned for channels the server cannot send to; such
+// deliveries fail without retries.
+var errNotConfigured = errors.New("channel is not configured on the server")
+
+// Message is what is sent, rendered from a template.
+type Message struct {
+	Title   string
+	Body    string // plain text
+	Link    string // to the report or page the message is about
+	AlertID *uint
+}
+
+// Sender sends a message to one destination of a channel type.
+type Sender interface {
+	Send(target, secret string, msg Message) error
+}
+
+// Notifier stores messages in the inbox and in the notification_deliveries
+// table, which a background loop sends to email and chat channels with
+// retries, the same way webhook deliveries are sent.
+type Notifier struct {
+	db           *gorm.DB
+	senders      map[string]Sender
+	baseURL      string
+	allowPrivate bool
+	timeout      time.Duration
+	outbox       *outbox.Outbox[models.NotificationDelivery]
+}
+
+func NewNotifier(db *gorm.DB, cfg config.NotifyConfig) *Notifier {
+	n := &Notifier{
+		db:           db,
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		allowPrivate: cfg.AllowPrivateNetworks,
+		timeout:      defaultTimeout,
+	}
+
+	outboxCfg := outbox.Config{
+		Name:         "notification",
+		SentColumn:   "sent_at",
+		MaxAttempts:  cfg.MaxAttempts,
+		Backoff:      backoff.Policy{Initial: defaultInitialBackoff, Max: defaultMaxBackoff},
+		PollInterval: defaultPollInterval,
+	}
+	if outboxCfg.MaxAttempts < 1 {
+		outboxCfg.MaxAttempts = defaultMaxAttempts
+	}
+	if t, err := time.ParseDuration(cfg.Timeout); err == nil && t > 0 {
+		n.timeout = t
+	}
+	outboxCfg.ClaimTimeout = 2 * n.timeout
+	if t, err := time.ParseDuration(cfg.InitialBackoff); err == nil && t > 0 {
+		outboxCfg.Backoff.Initial = t
+	}
+	if t, err := time.ParseDuration(cfg.MaxBackoff); err == nil && t > 0 {
+		outboxCfg.Backoff.Max = t
+	}
+	if t, err := time.ParseDuration(cfg.PollInterval); err == nil && t > 0 {
+		outboxCfg.PollInterval = t
+	}
+	n.outbox = outbox.New(db, outboxCfg, n.send)
+
+	// Chat robots answer directly; a redirect counts as a failed send
+	httpClient := netguard.NewClient(n.timeout, n.allowPrivate, false)
+	n.senders = map[string]Sender{
+		models.ChannelTypeEmail:    &smtpSender{cfg: cfg.SMTP, timeout: n.timeout},
+		models.ChannelTypeFeishu:   &feishuSender{httpClient: httpClient},
+		models.ChannelTypeDingTalk: &dingTalkSender{httpClient: httpClient},
+		models.ChannelTypeWeCom:    &weComSender{httpClient: httpClient},
+		models.ChannelTypeSlack:    &slackSender{httpClient: httpClient},
+	}
+
+	return n
+}
+
+// CheckURL refuses chat webhook URLs on private addresses unless they are
+// allowed.
+func (n *Notifier) CheckURL(raw string) error {
+	return netguard.CheckURL(raw, n.allowPrivate)
+}
+
+// ReportLink returns the frontend address of a video's report.
+func (n *Notifier) ReportLink(videoID uint) string {
+	return fmt.Sprintf("%s/reports/%d", n.baseURL, videoID)
+}
+
+// DigestLink returns the frontend address of a digest.
+func (n *Notifier) DigestLink(digestID uint) string {
+	return fmt.Sprintf("%s/digests/%d", n.baseURL, digestID)
+}
+
+// Enqueue sends msg to userID through the given alert channels (inbox,
+// email, chat; others are ignored). Pass the caller's transaction so the
+// message is only sent if the change it announces is committed; call Notify
+// afterwards.
+func (n *Notifier) Enqueue(tx *gorm.DB, userID uint, channels []string, msg Message) error {
+	for _, channel := range channels {
+		var err error
+		switch channel {
+		case models.ChannelInbox:
+			err = tx.Create(&models.Notification{
+				UserID:  userID,
+				AlertID: msg.AlertID,
+				Title:   msg.Title,
+				Body:    msg.Body,
+				Link:    msg.Link,
+			}).Error
+			if err != nil {
+				err = fmt.Errorf("failed to create notification: %w", err)
+			}
+		case models.ChannelEmail:
+			err = n.enqueueEmail(tx, userID, msg)
+		case models.ChannelChat:
+			err = n.enqueueDeliveries(tx, userID, models.ChatChannelTypes, msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueEmail queues msg for the user's email channels, or for the account
+// email if they have none.
+func (n *Notifier) enqueueEmail(tx *gorm.DB, userID uint, msg Message) error {
+	var count int64
+	if err := tx.Model(&models.NotificationChannel{}).
+		Where("user_id = ? AND type = ? AND active = ?", userID, models.ChannelTypeEmail, true).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	if count > 0 {
+		return n.enqueueDeliveries(tx, userID, []string{models.ChannelTypeEmail}, msg)
+	}
+
+	var user models.User
+	if err := tx.Select("id", "email").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	return n.createDelivery(tx, userID, nil, models.ChannelTypeEmail, user.Email, msg)
+}
+
+func (n *Notifier) enqueueDeliveries(tx *gorm.DB, userID uint, types []string, msg Message) error {
+	var channels []models.NotificationChannel
+	if err := tx.Where("user_id = ? AND type IN ? AND active = ?", userID, types, true).Find(&channels).Error; err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+
+	for i := range channels {
+		if err := n.createDelivery(tx, userID, &channels[i].ID, channels[i].Type, channels[i].Target, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) createDelivery(tx *gorm.DB, userID uint, channelID *uint, channelType, target string, msg Message) error {
+	now := time.Now()
+	delivery := models.NotificationDelivery{
+		UserID:        userID,
+		ChannelID:     channelID,
+		ChannelType:   channelType,
+		Target:        target,
+		AlertID:       msg.AlertID,
+		Title:         msg.Title,
+		Body:          msg.Body,
+		Link:          msg.Link,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: &now,
+	}
+	if err := tx.Create(&delivery).Error; err != nil {
+		return fmt.Errorf("failed to create notification delivery: %w", err)
+	}
+	return nil
+}
+
+// Notify wakes up the delivery loop. It never blocks.
+func (n *Notifier) Notify() {
+	n.outbox.Notify()
+}
+
+// Start runs the delivery loop in the background.
+func (n *Notifier) Start() {
+	n.outbox.Start()
+}
+
+// Test sends a test message to a channel right away, without retries, and
+// returns the logged delivery.
+func (n *Notifier) Test(channel *models.NotificationChannel) (*models.NotificationDelivery, error) {
+	msg, err := RenderTest(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.NotificationDelivery{
+		UserID:      channel.UserID,
+		ChannelID:   &channel.ID,
+		ChannelType: channel.Type,
+		Target:      channel.Target,
+		Title:       msg.Title,
+		Body:        msg.Body,
+		Status:      models.DeliveryStatusPending,
+	}
+	if err := n.db.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notification delivery: %w", err)
+	}
+
+	return n.outbox.SendNow(delivery.ID)
+}
+
+// send delivers a message through the sender of its channel type.
+func (n *Notifier) send(delivery *models.NotificationDelivery) (map[string]interface{}, error) {
+	var secret string
+	if delivery.ChannelID != nil {
+		var channel models.NotificationChannel
+		if err := n.db.First(&channel, *delivery.ChannelID).Error; err != nil {
+			// The channel was deleted; nothing to send to
+			return nil, outbox.Permanent(errors.New("channel no longer exists"))
+		}
+		secret = channel.Secret
+	}
+
+	sender, ok := n.senders[delivery.ChannelType]
+	if !ok {
+		return nil, outbox.Permanent(fmt.Errorf("unknown channel type %q: %w", delivery.ChannelType, errNotConfigured))
+	}
+	err := sender.Send(delivery.Target, secret, Message{
+		Title: delivery.Title,
+		Body:  delivery.Body,
+		Link:  delivery.Link,
+	})
+	if errors.Is(err, errNotConfigured) {
+		return nil, outbox.Permanent(err)
+	}
+	return nil, err
+}
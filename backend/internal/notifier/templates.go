@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"opinion-monitor/internal/models"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"severity":  severityLabel,
+	"location":  locationLabel,
+	"timestamp": formatTimestamp,
+	"period":    periodLabel,
+	"date":      formatDate,
+	"change":    formatChange,
+}
+
+// templates holds one set per file, since every file defines its own
+// "title" and "body" templates.
+var templates = func() map[string]*template.Template {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	sets := make(map[string]*template.Template, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		sets[name] = template.Must(template.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/"+name))
+	}
+	return sets
+}()
+
+// AlertData is what the alert template is rendered with.
+type AlertData struct {
+	AlertID        uint
+	Source         string // watchlist or rule
+	Severity       string
+	RuleName       string
+	WatchlistName  string
+	Term           string
+	Location       string
+	Timestamp      *float64
+	Occurrences    int
+	Snippet        string
+	VideoID        uint
+	Filename       string
+	RiskLevel      string
+	SentimentScore float64
+	Link           string
+}
+
+// RenderAlert renders the message announcing an alert.
+func RenderAlert(data AlertData) (Message, error) {
+	msg, err := render("alert.tmpl", data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Link = data.Link
+	msg.AlertID = &data.AlertID
+	return msg, nil
+}
+
+// DigestData is what the digest template is rendered with.
+type DigestData struct {
+	DigestID uint
+	Name     string // of the schedule
+	Period   string
+	Start    time.Time
+	End      time.Time
+	Stats    *models.DigestStats
+	Summary  string
+	Link     string
+}
+
+// RenderDigest renders the message delivering a digest.
+func RenderDigest(data DigestData) (Message, error) {
+	msg, err := render("digest.tmpl", data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Link = data.Link
+	return msg, nil
+}
+
+// RenderTest renders the message sent by the channel test endpoint.
+func RenderTest(channel *models.NotificationChannel) (Message, error) {
+	return render("test.tmpl", channel)
+}
+
+func render(name string, data interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("template %s not found", name)
+	}
+
+	var title, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&title, "title", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s title: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s body: %w", name, err)
+	}
+
+	return Message{
+		Title: strings.TrimSpace(title.String()),
+		Body:  strings.TrimSpace(body.String()),
+	}, nil
+}
+
+func severityLabel(severity string) string {
+	switch severity {
+	case "high":
+		return "高"
+	case "medium":
+		return "中"
+	case "low":
+		return "低"
+	}
+	return "提示"
+}
+
+func locationLabel(location string) string {
+	switch location {
+	case models.AlertLocationCoverText:
+		return "画面文字"
+	case models.AlertLocationTranscript:
+		return "音频转录"
+	case models.AlertLocationKeyTopics:
+		return "核心话题"
+	}
+	return location
+}
+
+func periodLabel(period string) string {
+	switch period {
+	case models.DigestPeriodDaily:
+		return "日报"
+	case models.DigestPeriodWeekly:
+		return "周报"
+	}
+	return "摘要"
+}
+
+func formatDate(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatChange renders a difference with its sign, e.g. +3 or -2.
+func formatChange(delta int) string {
+	if delta == 0 {
+		return "±0"
+	}
+	return fmt.Sprintf("%+d", delta)
+}
+
+// formatTimestamp renders seconds as m:ss or h:mm:ss.
+func formatTimestamp(seconds *float64) string {
+	total := int(*seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
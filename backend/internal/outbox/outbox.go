@@ -0,0 +1,187 @@
+// Package outbox sends rows of a delivery table (webhook_deliveries,
+// notification_deliveries) from a background loop. Rows are created inside
+// the transaction of the change they announce; the loop claims the ones
+// whose next_attempt_at is due, sends them and reschedules failures with
+// exponential backoff until they succeed or run out of attempts.
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"opinion-monitor/internal/backoff"
+	"opinion-monitor/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimBatch is how many due deliveries are picked up per poll.
+const claimBatch = 20
+
+// permanentError marks a failed send that will not succeed on a retry,
+// e.g. because its destination was deleted.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the delivery fails right away instead of being retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// SendFunc sends one delivery. It returns extra columns to record with the
+// outcome (e.g. the response), which may be nil, and the error of the send.
+type SendFunc[T any] func(delivery *T) (map[string]interface{}, error)
+
+// Config describes a delivery table and how its rows are retried.
+type Config struct {
+	Name         string // used in log messages, e.g. "webhook"
+	SentColumn   string // set to the time of a successful send
+	MaxAttempts  int
+	Backoff      backoff.Policy
+	PollInterval time.Duration
+	// ClaimTimeout is how long a claimed delivery is hidden from other
+	// pollers; it must be longer than a send can take.
+	ClaimTimeout time.Duration
+}
+
+// Outbox delivers rows of model T, which must have the id, status,
+// attempts, error and next_attempt_at columns.
+type Outbox[T any] struct {
+	db     *gorm.DB
+	cfg    Config
+	send   SendFunc[T]
+	notify chan struct{}
+}
+
+func New[T any](db *gorm.DB, cfg Config, send SendFunc[T]) *Outbox[T] {
+	return &Outbox[T]{
+		db:     db,
+		cfg:    cfg,
+		send:   send,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// dueDelivery holds the columns needed to claim a delivery.
+type dueDelivery struct {
+	ID            uint
+	Attempts      int
+	NextAttemptAt *time.Time
+}
+
+// Notify wakes up the delivery loop. It never blocks.
+func (o *Outbox[T]) Notify() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the delivery loop in the background.
+func (o *Outbox[T]) Start() {
+	go func() {
+		for {
+			o.deliverDue()
+
+			timer := time.NewTimer(o.cfg.PollInterval)
+			select {
+			case <-o.notify:
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+	}()
+	log.Printf("Started %s dispatcher", o.cfg.Name)
+}
+
+// SendNow sends a delivery that was just created once, without retries,
+// and returns it with the outcome recorded.
+func (o *Outbox[T]) SendNow(id uint) (*T, error) {
+	o.attempt(dueDelivery{ID: id}, false)
+
+	var delivery T
+	if err := o.db.First(&delivery, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load %s delivery: %w", o.cfg.Name, err)
+	}
+	return &delivery, nil
+}
+
+// deliverDue sends every pending delivery whose next attempt is due.
+func (o *Outbox[T]) deliverDue() {
+	var due []dueDelivery
+	if err := o.db.Model(new(T)).
+		Select("id", "attempts", "next_attempt_at").
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(claimBatch).
+		Find(&due).Error; err != nil {
+		log.Printf("Warning: failed to load %s deliveries: %v", o.cfg.Name, err)
+		return
+	}
+
+	for _, d := range due {
+		if !o.claim(d) {
+			continue
+		}
+		o.attempt(d, true)
+	}
+}
+
+// claim pushes the next attempt of a delivery past the claim timeout so
+// another instance polling at the same time does not send it twice.
+func (o *Outbox[T]) claim(d dueDelivery) bool {
+	lockedUntil := time.Now().Add(o.cfg.ClaimTimeout)
+	result := o.db.Model(new(T)).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", d.ID, models.DeliveryStatusPending, d.NextAttemptAt).
+		Update("next_attempt_at", lockedUntil)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// attempt sends a delivery once and records the outcome. Failed deliveries
+// are rescheduled with backoff when retry is set and attempts remain.
+func (o *Outbox[T]) attempt(d dueDelivery, retry bool) {
+	var delivery T
+	if err := o.db.First(&delivery, d.ID).Error; err != nil {
+		log.Printf("Warning: failed to load %s delivery %d: %v", o.cfg.Name, d.ID, err)
+		return
+	}
+
+	attempts := d.Attempts + 1
+	updates, err := o.send(&delivery)
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["attempts"] = attempts
+	updates["error"] = ""
+	now := time.Now()
+
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		updates["status"] = models.DeliveryStatusSucceeded
+		updates["next_attempt_at"] = nil
+		updates[o.cfg.SentColumn] = now
+	case retry && attempts < o.cfg.MaxAttempts && !errors.As(err, &permanent):
+		next := now.Add(o.cfg.Backoff.Delay(attempts))
+		updates["next_attempt_at"] = next
+		updates["error"] = err.Error()
+		log.Printf("Failed to send %s delivery %d, retrying at %s: %v", o.cfg.Name, d.ID, next.Format(time.RFC3339), err)
+	default:
+		updates["status"] = models.DeliveryStatusFailed
+		updates["next_attempt_at"] = nil
+		updates["error"] = err.Error()
+		log.Printf("Failed to send %s delivery %d after %d attempt(s): %v", o.cfg.Name, d.ID, attempts, err)
+	}
+
+	if err := o.db.Model(new(T)).Where("id = ?", d.ID).Updates(updates).Error; err != nil {
+		log.Printf("Warning: failed to update %s delivery %d: %v", o.cfg.Name, d.ID, err)
+	}
+}
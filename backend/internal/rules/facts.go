@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"encoding/json"
+	"opinion-monitor/internal/models"
+)
+
+// Facts are the values of a report that rules are evaluated against.
+type Facts struct {
+	RiskLevel          string
+	SentimentLabel     string
+	SentimentScore     float64
+	KeyTopics          []string
+	Duration           float64 // seconds, 0 if unknown
+	Version            float64
+	ProcessingTime     float64
+	CoverText          string
+	TranscriptText     string
+	TranscriptLanguage string
+	DetailedAnalysis   string
+	Filename           string
+	ModelChat          string
+	PromptVersion      string
+}
+
+// FactsFromReport collects the facts of a report. The report's Video must be
+// loaded for the video fields.
+func FactsFromReport(report *models.Report) *Facts {
+	facts := &Facts{
+		RiskLevel:          report.RiskLevel,
+		SentimentLabel:     report.SentimentLabel,
+		SentimentScore:     report.SentimentScore,
+		Duration:           report.Video.Duration,
+		Version:            float64(report.Version),
+		ProcessingTime:     report.ProcessingTime,
+		CoverText:          report.CoverText,
+		TranscriptText:     report.TranscriptText,
+		TranscriptLanguage: report.Video.TranscriptLanguage,
+		DetailedAnalysis:   report.DetailedAnalysis,
+		Filename:           report.Video.OriginalFilename,
+		ModelChat:          report.ModelChat,
+		PromptVersion:      report.PromptVersion,
+	}
+	json.Unmarshal([]byte(report.KeyTopics), &facts.KeyTopics)
+	return facts
+}
+
+type fieldKind int
+
+const (
+	kindNumber fieldKind = iota
+	kindText
+	kindLevel // ordered: low < medium < high
+	kindList
+)
+
+var riskLevels = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+func (k fieldKind) allows(op string) bool {
+	switch k {
+	case kindNumber:
+		return op == OpEq || op == OpNe || op == OpLt || op == OpLte || op == OpGt || op == OpGte
+	case kindLevel:
+		return op == OpEq || op == OpNe || op == OpLt || op == OpLte || op == OpGt || op == OpGte || op == OpIn
+	case kindText:
+		return op == OpEq || op == OpNe || op == OpIn || op == OpContains || op == OpMatches
+	case kindList:
+		return op == OpEq || op == OpContains || op == OpMatches
+	}
+	return false
+}
+
+type field struct {
+	kind fieldKind
+	get  func(f *Facts) interface{}
+}
+
+var fields = map[string]field{
+	"risk_level":          {kindLevel, func(f *Facts) interface{} { return f.RiskLevel }},
+	"sentiment_label":     {kindText, func(f *Facts) interface{} { return f.SentimentLabel }},
+	"sentiment_score":     {kindNumber, func(f *Facts) interface{} { return f.SentimentScore }},
+	"key_topics":          {kindList, func(f *Facts) interface{} { return f.KeyTopics }},
+	"duration":            {kindNumber, func(f *Facts) interface{} { return f.Duration }},
+	"version":             {kindNumber, func(f *Facts) interface{} { return f.Version }},
+	"processing_time":     {kindNumber, func(f *Facts) interface{} { return f.ProcessingTime }},
+	"cover_text":          {kindText, func(f *Facts) interface{} { return f.CoverText }},
+	"transcript_text":     {kindText, func(f *Facts) interface{} { return f.TranscriptText }},
+	"transcript_language": {kindText, func(f *Facts) interface{} { return f.TranscriptLanguage }},
+	"detailed_analysis":   {kindText, func(f *Facts) interface{} { return f.DetailedAnalysis }},
+	"filename":            {kindText, func(f *Facts) interface{} { return f.Filename }},
+	"model_chat":          {kindText, func(f *Facts) interface{} { return f.ModelChat }},
+	"prompt_version":      {kindText, func(f *Facts) interface{} { return f.PromptVersion }},
+}
@@ -0,0 +1,323 @@
+// Package rules evaluates alert rules against reports. A rule is a JSON
+// condition tree such as
+//
+//	{"all": [
+//	  {"field": "risk_level", "op": "eq", "value": "high"},
+//	  {"field": "sentiment_score", "op": "lt", "value": 0.2}
+//	]}
+//
+// where groups combine conditions with "all", "any" or "not", and leaves
+// compare one report field with a value.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxDepth bounds how deeply groups can be nested.
+const maxDepth = 8
+
+// Comparison operators.
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpIn       = "in"       // value is a list; equal to any of them
+	OpContains = "contains" // case-insensitive substring; for lists, of any element
+	OpMatches  = "matches"  // RE2 regular expression; for lists, on any element
+)
+
+var opSymbols = map[string]string{
+	OpEq: "=", OpNe: "!=", OpLt: "<", OpLte: "<=", OpGt: ">", OpGte: ">=",
+	OpIn: "in", OpContains: "contains", OpMatches: "matches",
+}
+
+// Condition is a node of a rule: exactly one of All, Any, Not or Field is set.
+type Condition struct {
+	All   []Condition `json:"all,omitempty"`
+	Any   []Condition `json:"any,omitempty"`
+	Not   *Condition  `json:"not,omitempty"`
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	number float64
+	text   string
+	list   []string
+	re     *regexp.Regexp
+}
+
+// Parse decodes and validates a condition.
+func Parse(data []byte) (*Condition, error) {
+	var c Condition
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+	if err := c.compile("condition", 0); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// compile checks the node and prepares its value for evaluation.
+func (c *Condition) compile(path string, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%s: rules cannot be nested more than %d levels deep", path, maxDepth)
+	}
+
+	set := 0
+	for _, ok := range []bool{c.All != nil, c.Any != nil, c.Not != nil, c.Field != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("%s: exactly one of all, any, not or field must be set", path)
+	}
+
+	switch {
+	case c.All != nil:
+		return compileGroup(c.All, path+".all", depth)
+	case c.Any != nil:
+		return compileGroup(c.Any, path+".any", depth)
+	case c.Not != nil:
+		return c.Not.compile(path+".not", depth+1)
+	}
+
+	f, ok := fields[c.Field]
+	if !ok {
+		return fmt.Errorf("%s: unknown field %q (expected one of %s)", path, c.Field, strings.Join(FieldNames(), ", "))
+	}
+	if !f.kind.allows(c.Op) {
+		return fmt.Errorf("%s: operator %q cannot be used with %s", path, c.Op, c.Field)
+	}
+
+	switch c.Op {
+	case OpIn:
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return fmt.Errorf("%s: %s needs a non-empty list value", path, c.Op)
+		}
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s: %s needs a list of strings", path, c.Op)
+			}
+			c.list = append(c.list, strings.ToLower(s))
+		}
+	case OpMatches:
+		s, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %s needs a string value", path, c.Op)
+		}
+		re, err := regexp.Compile("(?i)" + s)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		c.re = re
+	default:
+		if f.kind == kindNumber {
+			n, ok := c.Value.(float64)
+			if !ok {
+				return fmt.Errorf("%s: %s needs a number value", path, c.Field)
+			}
+			c.number = n
+			break
+		}
+
+		s, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %s needs a string value", path, c.Field)
+		}
+		c.text = strings.ToLower(s)
+		if f.kind == kindLevel {
+			if _, ok := riskLevels[c.text]; !ok {
+				return fmt.Errorf("%s: %s must be low, medium or high", path, c.Field)
+			}
+		}
+	}
+
+	return nil
+}
+
+func compileGroup(conditions []Condition, path string, depth int) error {
+	if len(conditions) == 0 {
+		return fmt.Errorf("%s: needs at least one condition", path)
+	}
+	for i := range conditions {
+		if err := conditions[i].compile(fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate reports whether facts satisfy the condition and, if so, which
+// comparisons made it true.
+func (c *Condition) Evaluate(facts *Facts) (bool, []string) {
+	switch {
+	case c.All != nil:
+		var reasons []string
+		for i := range c.All {
+			ok, r := c.All[i].Evaluate(facts)
+			if !ok {
+				return false, nil
+			}
+			reasons = append(reasons, r...)
+		}
+		return true, reasons
+	case c.Any != nil:
+		for i := range c.Any {
+			if ok, r := c.Any[i].Evaluate(facts); ok {
+				return true, r
+			}
+		}
+		return false, nil
+	case c.Not != nil:
+		if ok, _ := c.Not.Evaluate(facts); ok {
+			return false, nil
+		}
+		return true, []string{"not (" + c.Not.String() + ")"}
+	}
+
+	f := fields[c.Field]
+	actual := f.get(facts)
+	if !c.compare(f.kind, actual) {
+		return false, nil
+	}
+	return true, []string{fmt.Sprintf("%s (%s)", c.String(), formatActual(actual))}
+}
+
+func (c *Condition) compare(kind fieldKind, actual interface{}) bool {
+	switch kind {
+	case kindNumber:
+		return compareNumbers(actual.(float64), c.Op, c.number)
+	case kindLevel:
+		if c.Op == OpIn {
+			return containsString(c.list, strings.ToLower(actual.(string)))
+		}
+		a, ok := riskLevels[strings.ToLower(actual.(string))]
+		if !ok {
+			return c.Op == OpNe
+		}
+		return compareNumbers(float64(a), c.Op, float64(riskLevels[c.text]))
+	case kindText:
+		s := strings.ToLower(actual.(string))
+		switch c.Op {
+		case OpEq:
+			return s == c.text
+		case OpNe:
+			return s != c.text
+		case OpIn:
+			return containsString(c.list, s)
+		case OpContains:
+			return strings.Contains(s, c.text)
+		case OpMatches:
+			return c.re.MatchString(s)
+		}
+	case kindList:
+		for _, item := range actual.([]string) {
+			s := strings.ToLower(item)
+			switch {
+			case c.Op == OpEq && s == c.text,
+				c.Op == OpContains && strings.Contains(s, c.text),
+				c.Op == OpMatches && c.re.MatchString(s):
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String renders a condition as a short expression, e.g.
+// "risk_level = high AND sentiment_score < 0.2".
+func (c *Condition) String() string {
+	join := func(conditions []Condition, sep string) string {
+		parts := make([]string, len(conditions))
+		for i := range conditions {
+			parts[i] = conditions[i].String()
+			if conditions[i].All != nil || conditions[i].Any != nil {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, sep)
+	}
+
+	switch {
+	case c.All != nil:
+		return join(c.All, " AND ")
+	case c.Any != nil:
+		return join(c.Any, " OR ")
+	case c.Not != nil:
+		return "NOT (" + c.Not.String() + ")"
+	}
+
+	value := fmt.Sprint(c.Value)
+	if list, ok := c.Value.([]interface{}); ok {
+		parts := make([]string, len(list))
+		for i, v := range list {
+			parts[i] = fmt.Sprint(v)
+		}
+		value = "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprintf("%s %s %s", c.Field, opSymbols[c.Op], value)
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case OpEq:
+		return a == b
+	case OpNe:
+		return a != b
+	case OpLt:
+		return a < b
+	case OpLte:
+		return a <= b
+	case OpGt:
+		return a > b
+	case OpGte:
+		return a >= b
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func formatActual(actual interface{}) string {
+	switch v := actual.(type) {
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	case string:
+		if runes := []rune(v); len(runes) > 40 {
+			return string(runes[:40]) + "…"
+		}
+		return v
+	}
+	return fmt.Sprint(actual)
+}
+
+// FieldNames lists the report fields rules can use.
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// nested wraps leaf in depth "not" groups.
+func nested(leaf string, depth int) string {
+	return strings.Repeat(`{"not": `, depth) + leaf + strings.Repeat(`}`, depth)
+}
+
+const highRisk = `{"field": "risk_level", "op": "eq", "value": "high"}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr string
+	}{
+		{"leaf", highRisk, ""},
+		{"all", `{"all": [` + highRisk + `, {"field": "sentiment_score", "op": "lt", "value": 0.2}]}`, ""},
+		{"in list", `{"field": "sentiment_label", "op": "in", "value": ["negative", "neutral"]}`, ""},
+		{"pattern", `{"field": "transcript_text", "op": "matches", "value": "^rumou?r"}`, ""},
+		{"max depth", nested(highRisk, maxDepth), ""},
+		{"too deep", nested(highRisk, maxDepth+1), "nested more than 8 levels"},
+		{"too deep in groups", `{"any": [` + nested(`{"all": [`+highRisk+`]}`, maxDepth-1) + `]}`, "nested more than 8 levels"},
+		{"invalid json", `{"all": [`, "invalid rule"},
+		{"empty node", `{}`, "exactly one of"},
+		{"two kinds", `{"all": [` + highRisk + `], "field": "risk_level"}`, "exactly one of"},
+		{"empty group", `{"any": []}`, "at least one condition"},
+		{"unknown field", `{"field": "likes", "op": "gt", "value": 1}`, `unknown field "likes"`},
+		{"operator for kind", `{"field": "sentiment_score", "op": "contains", "value": "x"}`, `operator "contains" cannot be used`},
+		{"number value", `{"field": "duration", "op": "gt", "value": "60"}`, "needs a number value"},
+		{"string value", `{"field": "filename", "op": "eq", "value": 3}`, "needs a string value"},
+		{"risk level", `{"field": "risk_level", "op": "eq", "value": "critical"}`, "must be low, medium or high"},
+		{"empty in", `{"field": "sentiment_label", "op": "in", "value": []}`, "non-empty list"},
+		{"in of numbers", `{"field": "sentiment_label", "op": "in", "value": [1]}`, "list of strings"},
+		{"bad pattern", `{"field": "cover_text", "op": "matches", "value": "("}`, "invalid pattern"},
+		{"error path", `{"all": [` + highRisk + `, {"not": {"field": "x", "op": "eq", "value": 1}}]}`, "condition.all[1].not:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.rule))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Parse() error = %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Parse() succeeded, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Parse() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	facts := &Facts{
+		RiskLevel:      "Medium",
+		SentimentLabel: "negative",
+		SentimentScore: 0.15,
+		KeyTopics:      []string{"Food Safety", "recall"},
+		Duration:       90,
+		TranscriptText: "Rumours about the factory spread fast",
+	}
+
+	tests := []struct {
+		name        string
+		rule        string
+		want        bool
+		wantReasons []string
+	}{
+		{"number lt", `{"field": "sentiment_score", "op": "lt", "value": 0.2}`, true, []string{"sentiment_score < 0.2 (0.15)"}},
+		{"number gte false", `{"field": "duration", "op": "gte", "value": 120}`, false, nil},
+		{"level order", `{"field": "risk_level", "op": "gte", "value": "medium"}`, true, []string{"risk_level >= medium (Medium)"}},
+		{"level below", `{"field": "risk_level", "op": "gt", "value": "medium"}`, false, nil},
+		{"level in", `{"field": "risk_level", "op": "in", "value": ["medium", "high"]}`, true, []string{"risk_level in [medium, high] (Medium)"}},
+		{"text contains ignores case", `{"field": "transcript_text", "op": "contains", "value": "FACTORY"}`, true, nil},
+		{"text matches", `{"field": "transcript_text", "op": "matches", "value": "^rumou?rs"}`, true, nil},
+		{"text ne", `{"field": "sentiment_label", "op": "ne", "value": "negative"}`, false, nil},
+		{"list eq any element", `{"field": "key_topics", "op": "eq", "value": "food safety"}`, true, []string{"key_topics = food safety ([Food Safety, recall])"}},
+		{"list contains", `{"field": "key_topics", "op": "contains", "value": "call"}`, true, nil},
+		{"list no match", `{"field": "key_topics", "op": "eq", "value": "safety"}`, false, nil},
+		{
+			"all collects reasons",
+			`{"all": [{"field": "risk_level", "op": "eq", "value": "medium"}, {"field": "duration", "op": "gt", "value": 60}]}`,
+			true,
+			[]string{"risk_level = medium (Medium)", "duration > 60 (90)"},
+		},
+		{
+			"all fails on one",
+			`{"all": [{"field": "risk_level", "op": "eq", "value": "medium"}, {"field": "duration", "op": "gt", "value": 100}]}`,
+			false,
+			nil,
+		},
+		{
+			"any first match",
+			`{"any": [{"field": "duration", "op": "gt", "value": 100}, {"field": "sentiment_label", "op": "eq", "value": "negative"}]}`,
+			true,
+			[]string{"sentiment_label = negative (negative)"},
+		},
+		{"not", `{"not": {"field": "risk_level", "op": "eq", "value": "high"}}`, true, []string{"not (risk_level = high)"}},
+		{"double not", nested(highRisk, 2), false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.rule))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, reasons := c.Evaluate(facts)
+			if got != tt.want {
+				t.Fatalf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if tt.wantReasons != nil && strings.Join(reasons, "; ") != strings.Join(tt.wantReasons, "; ") {
+				t.Fatalf("Evaluate() reasons = %q, want %q", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestEvaluateUnknownRiskLevel(t *testing.T) {
+	// Reports with a level outside low, medium and high only satisfy ne
+	facts := &Facts{RiskLevel: "unknown"}
+	for op, want := range map[string]bool{OpEq: false, OpNe: true, OpLt: false, OpGte: false} {
+		c, err := Parse([]byte(`{"field": "risk_level", "op": "` + op + `", "value": "low"}`))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got, _ := c.Evaluate(facts); got != want {
+			t.Errorf("%s: Evaluate() = %v, want %v", op, got, want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	c, err := Parse([]byte(`{"any": [{"all": [` + highRisk + `, {"field": "duration", "op": "lte", "value": 30}]}, {"not": {"field": "sentiment_label", "op": "in", "value": ["positive"]}}]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := "(risk_level = high AND duration <= 30) OR NOT (sentiment_label in [positive])"
+	if got := c.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
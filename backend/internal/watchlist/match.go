@@ -0,0 +1,153 @@
+package watchlist
+
+import (
+	"fmt"
+	"opinion-monitor/internal/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// snippetRadius is how many runes of context are quoted on each side of a match.
+const snippetRadius = 40
+
+// Source is a piece of analyzed text that terms are matched against.
+type Source struct {
+	Location  string
+	Text      string
+	Timestamp *float64 // seconds into the video, if the text has a position
+}
+
+// Hit is a term found in the sources: the first occurrence per location is
+// quoted, and the rest are only counted.
+type Hit struct {
+	Watchlist   *models.Watchlist
+	Term        *models.WatchlistTerm
+	Location    string
+	Snippet     string
+	Timestamp   *float64
+	Occurrences int
+}
+
+// ForUser loads the active watchlists, with their terms, that apply to the
+// videos of userID: the user's own and those shared within their tenant.
+func ForUser(db *gorm.DB, userID uint) ([]models.Watchlist, error) {
+	var owner models.User
+	if err := db.Select("id", "tenant").First(&owner, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	query := db.Preload("Terms").Where("active = ?", true)
+	if owner.Tenant != "" {
+		query = query.Where("user_id = ? OR (shared = ? AND tenant = ?)", userID, true, owner.Tenant)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var watchlists []models.Watchlist
+	if err := query.Order("id").Find(&watchlists).Error; err != nil {
+		return nil, fmt.Errorf("failed to load watchlists: %w", err)
+	}
+	return watchlists, nil
+}
+
+// Compile turns a term into the expression it is matched with.
+func Compile(term *models.WatchlistTerm) (*regexp.Regexp, error) {
+	var expr string
+	switch term.MatchType {
+	case models.MatchTypeContains, "":
+		expr = regexp.QuoteMeta(term.Term)
+	case models.MatchTypeWord:
+		expr = `\b` + regexp.QuoteMeta(term.Term) + `\b`
+	case models.MatchTypeRegex:
+		expr = term.Term
+	default:
+		return nil, fmt.Errorf("unknown match type %q", term.MatchType)
+	}
+	if !term.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", term.Term, err)
+	}
+	return re, nil
+}
+
+// Match looks for every term of the watchlists in the sources. Terms that do
+// not compile are skipped.
+func Match(watchlists []models.Watchlist, sources []Source) []Hit {
+	var hits []Hit
+	for i := range watchlists {
+		for j := range watchlists[i].Terms {
+			term := &watchlists[i].Terms[j]
+			re, err := Compile(term)
+			if err != nil || strings.TrimSpace(term.Term) == "" {
+				continue
+			}
+
+			// One hit per location, in the order locations first match
+			byLocation := make(map[string]int)
+			for _, source := range sources {
+				matches := nonEmpty(re.FindAllStringIndex(source.Text, -1))
+				if len(matches) == 0 {
+					continue
+				}
+
+				if k, ok := byLocation[source.Location]; ok {
+					hits[k].Occurrences += len(matches)
+					continue
+				}
+				byLocation[source.Location] = len(hits)
+				hits = append(hits, Hit{
+					Watchlist:   &watchlists[i],
+					Term:        term,
+					Location:    source.Location,
+					Snippet:     snippet(source.Text, matches[0][0], matches[0][1]),
+					Timestamp:   source.Timestamp,
+					Occurrences: len(matches),
+				})
+			}
+		}
+	}
+	return hits
+}
+
+// nonEmpty drops zero-length matches, which a regex term like "a*" produces
+// everywhere.
+func nonEmpty(matches [][]int) [][]int {
+	kept := matches[:0]
+	for _, m := range matches {
+		if m[1] > m[0] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// snippet quotes text[start:end] with some context on both sides, on a
+// single line.
+func snippet(text string, start, end int) string {
+	from := start
+	for n := 0; n < snippetRadius && from > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := end
+	for n := 0; n < snippetRadius && to < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	quoted := strings.Join(strings.Fields(text[from:to]), " ")
+	if from > 0 {
+		quoted = "…" + quoted
+	}
+	if to < len(text) {
+		quoted += "…"
+	}
+	return quoted
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"opinion-monitor/internal/backoff"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/netguard"
+	"opinion-monitor/internal/outbox"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 30 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultTimeout        = 10 * time.Second
+	defaultPollInterval   = 5 * time.Second
+
+	// maxResponseBody is how much of a response is kept in the delivery log.
+	maxResponseBody = 2048
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + hex HMAC of "<timestamp>.<body>"
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers webhook events. Deliveries are stored in the
+// webhook_deliveries table first and sent by a background loop, so they
+// survive restarts and failed attempts are retried with exponential backoff.
+type Dispatcher struct {
+	db           *gorm.DB
+	httpClient   *http.Client
+	allowPrivate bool
+	outbox       *outbox.Outbox[models.WebhookDelivery]
+}
+
+func NewDispatcher(db *gorm.DB, cfg config.WebhooksConfig) *Dispatcher {
+	d := &Dispatcher{
+		db:           db,
+		allowPrivate: cfg.AllowPrivateNetworks,
+	}
+
+	outboxCfg := outbox.Config{
+		Name:         "webhook",
+		SentColumn:   "delivered_at",
+		MaxAttempts:  cfg.MaxAttempts,
+		Backoff:      backoff.Policy{Initial: defaultInitialBackoff, Max: defaultMaxBackoff},
+		PollInterval: defaultPollInterval,
+	}
+	if outboxCfg.MaxAttempts < 1 {
+		outboxCfg.MaxAttempts = defaultMaxAttempts
+	}
+	timeout := defaultTimeout
+	if t, err := time.ParseDuration(cfg.Timeout); err == nil && t > 0 {
+		timeout = t
+	}
+	outboxCfg.ClaimTimeout = 2 * timeout
+	// Redirects are not followed; a 3xx counts as a failed delivery
+	d.httpClient = netguard.NewClient(timeout, d.allowPrivate, false)
+	if t, err := time.ParseDuration(cfg.InitialBackoff); err == nil && t > 0 {
+		outboxCfg.Backoff.Initial = t
+	}
+	if t, err := time.ParseDuration(cfg.MaxBackoff); err == nil && t > 0 {
+		outboxCfg.Backoff.Max = t
+	}
+	if t, err := time.ParseDuration(cfg.PollInterval); err == nil && t > 0 {
+		outboxCfg.PollInterval = t
+	}
+	d.outbox = outbox.New(db, outboxCfg, d.send)
+
+	return d
+}
+
+// CheckURL refuses webhook URLs on private addresses unless they are allowed.
+func (d *Dispatcher) CheckURL(raw string) error {
+	return netguard.CheckURL(raw, d.allowPrivate)
+}
+
+// GenerateSecret returns a random signing secret for a new webhook.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Sign computes the signature header value for a delivery body. Receivers
+// should recompute it and compare in constant time, and reject stale
+// timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue stores a delivery of event for every active webhook of userID
+// subscribed to it. Pass the caller's transaction so deliveries are only
+// created if the change they announce is committed; call Notify afterwards.
+func (d *Dispatcher) Enqueue(tx *gorm.DB, userID uint, event string, data interface{}) error {
+	var hooks []models.Webhook
+	if err := tx.Where("user_id = ? AND active = ?", userID, true).Find(&hooks).Error; err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	var body []byte
+	now := time.Now()
+	for _, hook := range hooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			body, err = json.Marshal(Payload{Event: event, CreatedAt: now, Data: data})
+			if err != nil {
+				return fmt.Errorf("failed to encode webhook payload: %w", err)
+			}
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: &now,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Notify wakes up the delivery loop. It never blocks.
+func (d *Dispatcher) Notify() {
+	d.outbox.Notify()
+}
+
+// Start runs the delivery loop in the background.
+func (d *Dispatcher) Start() {
+	d.outbox.Start()
+}
+
+// Test sends a ping to hook right away, without retries, and returns the
+// logged delivery.
+func (d *Dispatcher) Test(hook *models.Webhook) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(Payload{
+		Event:     models.WebhookEventPing,
+		CreatedAt: time.Now(),
+		Data:      map[string]interface{}{"webhook_id": hook.ID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     models.WebhookEventPing,
+		Payload:   string(body),
+		Status:    models.DeliveryStatusPending,
+	}
+	if err := d.db.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return d.outbox.SendNow(delivery.ID)
+}
+
+// send delivers to the delivery's webhook and returns the response to log.
+func (d *Dispatcher) send(delivery *models.WebhookDelivery) (map[string]interface{}, error) {
+	var hook models.Webhook
+	if err := d.db.First(&hook, delivery.WebhookID).Error; err != nil {
+		// The webhook was deleted; nothing to send to
+		return nil, outbox.Permanent(errors.New("webhook no longer exists"))
+	}
+
+	code, respBody, err := d.post(&hook, delivery)
+	return map[string]interface{}{
+		"response_code": code,
+		"response_body": respBody,
+	}, err
+}
+
+// post sends the signed payload. Any non-2xx response is an error.
+func (d *Dispatcher) post(hook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "opinion-monitor-webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	resp, private, err := netguard.Do(d.httpClient, req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Bodies from internal hosts are not shown back to the webhook owner
+	var respBody string
+	if !private {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		respBody = string(b)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, respBody, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, respBody, nil
+}
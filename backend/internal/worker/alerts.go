@@ -0,0 +1,284 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/notifier"
+	"opinion-monitor/internal/rules"
+	"opinion-monitor/internal/watchlist"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// raiseWatchlistAlerts matches the owner's watchlists against the analyzed
+// text of a new report and records an alert per term and location. A term
+// that already has an unresolved alert on the video is not raised again, so
+// reanalysis does not duplicate alerts.
+func (wp *WorkerPool) raiseWatchlistAlerts(tx *gorm.DB, state *pipelineState, reportRecord *models.Report) error {
+	watchlists, err := watchlist.ForUser(tx, state.Video.UserID)
+	if err != nil {
+		return err
+	}
+	if len(watchlists) == 0 {
+		return nil
+	}
+
+	sources, err := alertSources(tx, state)
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range watchlist.Match(watchlists, sources) {
+		var open int64
+		if err := tx.Model(&models.Alert{}).
+			Where("video_id = ? AND term_id = ? AND location = ? AND status <> ?", state.Video.ID, hit.Term.ID, hit.Location, models.AlertStatusResolved).
+			Count(&open).Error; err != nil {
+			return fmt.Errorf("failed to check existing alerts: %w", err)
+		}
+		if open > 0 {
+			continue
+		}
+
+		alert := models.Alert{
+			UserID:      state.Video.UserID,
+			VideoID:     state.Video.ID,
+			ReportID:    reportRecord.ID,
+			Source:      models.AlertSourceWatchlist,
+			WatchlistID: &hit.Watchlist.ID,
+			TermID:      hit.Term.ID,
+			Term:        hit.Term.Term,
+			Category:    hit.Term.Category,
+			Location:    hit.Location,
+			Snippet:     hit.Snippet,
+			Timestamp:   hit.Timestamp,
+			Occurrences: hit.Occurrences,
+			Status:      models.AlertStatusOpen,
+		}
+		if err := tx.Create(&alert).Error; err != nil {
+			return fmt.Errorf("failed to create alert: %w", err)
+		}
+
+		notice := alertNotice{
+			Channels:      hit.Watchlist.Channels,
+			RecipientID:   hit.Watchlist.UserID,
+			WatchlistName: hit.Watchlist.Name,
+		}
+		if err := wp.dispatchAlert(tx, state, reportRecord, &alert, notice); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertSources lists the text watchlists are matched against: the distinct
+// frame texts, the transcript segments and the report's key topics.
+func alertSources(tx *gorm.DB, state *pipelineState) ([]watchlist.Source, error) {
+	var sources []watchlist.Source
+
+	if len(state.FrameTexts) > 0 {
+		for _, t := range state.FrameTexts {
+			if t.Duplicate {
+				continue
+			}
+			timestamp := t.Timestamp
+			sources = append(sources, watchlist.Source{Location: models.AlertLocationCoverText, Text: t.Text, Timestamp: &timestamp})
+		}
+	} else if state.CoverText != "" {
+		sources = append(sources, watchlist.Source{Location: models.AlertLocationCoverText, Text: state.CoverText})
+	}
+
+	var segments []models.TranscriptSegment
+	if err := tx.Where("video_id = ?", state.Video.ID).Order("position").Find(&segments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load transcript segments: %w", err)
+	}
+	if len(segments) > 0 {
+		for _, seg := range segments {
+			start := seg.Start
+			sources = append(sources, watchlist.Source{Location: models.AlertLocationTranscript, Text: seg.Text, Timestamp: &start})
+		}
+	} else if state.TranscriptText != "" {
+		sources = append(sources, watchlist.Source{Location: models.AlertLocationTranscript, Text: state.TranscriptText})
+	}
+
+	for _, topic := range state.Sentiment.KeyTopics {
+		sources = append(sources, watchlist.Source{Location: models.AlertLocationKeyTopics, Text: topic})
+	}
+
+	return sources, nil
+}
+
+// evaluateAlertRules runs the owner's alert rules against a new report and
+// records an alert for each rule it satisfies, unless the rule already has an
+// unresolved alert on the video.
+func (wp *WorkerPool) evaluateAlertRules(tx *gorm.DB, state *pipelineState, reportRecord *models.Report) error {
+	var alertRules []models.AlertRule
+	if err := tx.Where("user_id = ? AND active = ?", state.Video.UserID, true).Order("id").Find(&alertRules).Error; err != nil {
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+	if len(alertRules) == 0 {
+		return nil
+	}
+
+	report := *reportRecord
+	report.Video = state.Video
+	if state.TranscriptLanguage != "" {
+		report.Video.TranscriptLanguage = state.TranscriptLanguage
+	}
+	facts := rules.FactsFromReport(&report)
+
+	for i := range alertRules {
+		rule := &alertRules[i]
+		condition, err := rules.Parse([]byte(rule.Condition))
+		if err != nil {
+			log.Printf("Warning: skipping alert rule %d: %v", rule.ID, err)
+			continue
+		}
+
+		matched, reasons := condition.Evaluate(facts)
+		if !matched {
+			continue
+		}
+
+		var open int64
+		if err := tx.Model(&models.Alert{}).
+			Where("video_id = ? AND rule_id = ? AND status <> ?", state.Video.ID, rule.ID, models.AlertStatusResolved).
+			Count(&open).Error; err != nil {
+			return fmt.Errorf("failed to check existing alerts: %w", err)
+		}
+		if open > 0 {
+			continue
+		}
+
+		alert := models.Alert{
+			UserID:      state.Video.UserID,
+			VideoID:     state.Video.ID,
+			ReportID:    reportRecord.ID,
+			Source:      models.AlertSourceRule,
+			RuleID:      &rule.ID,
+			Severity:    rule.Severity,
+			Snippet:     strings.Join(reasons, "; "),
+			Occurrences: 1,
+			Status:      models.AlertStatusOpen,
+		}
+		if err := tx.Create(&alert).Error; err != nil {
+			return fmt.Errorf("failed to create alert: %w", err)
+		}
+
+		notice := alertNotice{
+			Channels:    rule.Channels,
+			RecipientID: rule.UserID,
+			RuleName:    rule.Name,
+			Reasons:     reasons,
+		}
+		if err := wp.dispatchAlert(tx, state, reportRecord, &alert, notice); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertNotice describes where a new alert is sent, taken from the watchlist
+// or rule that raised it.
+type alertNotice struct {
+	Channels      string // comma separated
+	RecipientID   uint   // owner of the watchlist or rule
+	WatchlistName string
+	RuleName      string
+	Reasons       []string
+}
+
+// alertWebhookData is the data of alert.triggered webhook events.
+type alertWebhookData struct {
+	AlertID          uint      `json:"alert_id"`
+	Source           string    `json:"source"`
+	RuleID           *uint     `json:"rule_id,omitempty"`
+	RuleName         string    `json:"rule_name,omitempty"`
+	Severity         string    `json:"severity,omitempty"`
+	Reasons          []string  `json:"reasons,omitempty"`
+	WatchlistID      *uint     `json:"watchlist_id,omitempty"`
+	WatchlistName    string    `json:"watchlist_name,omitempty"`
+	Term             string    `json:"term,omitempty"`
+	Location         string    `json:"location,omitempty"`
+	Timestamp        *float64  `json:"timestamp,omitempty"`
+	Snippet          string    `json:"snippet"`
+	ReportID         uint      `json:"report_id"`
+	VideoID          uint      `json:"video_id"`
+	OriginalFilename string    `json:"original_filename"`
+	RiskLevel        string    `json:"risk_level"`
+	SentimentScore   float64   `json:"sentiment_score"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// dispatchAlert sends a new alert to the channels of the watchlist or rule
+// that raised it: webhook deliveries and notifications are queued in the
+// transaction that creates the alert.
+func (wp *WorkerPool) dispatchAlert(tx *gorm.DB, state *pipelineState, reportRecord *models.Report, alert *models.Alert, notice alertNotice) error {
+	var channels []string
+	for _, ch := range strings.Split(notice.Channels, ",") {
+		if ch = strings.TrimSpace(ch); ch != "" {
+			channels = append(channels, ch)
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	for _, ch := range channels {
+		if ch != models.ChannelWebhook || wp.webhooks == nil {
+			continue
+		}
+		data := alertWebhookData{
+			AlertID:          alert.ID,
+			Source:           alert.Source,
+			RuleID:           alert.RuleID,
+			RuleName:         notice.RuleName,
+			Severity:         alert.Severity,
+			Reasons:          notice.Reasons,
+			WatchlistID:      alert.WatchlistID,
+			WatchlistName:    notice.WatchlistName,
+			Term:             alert.Term,
+			Location:         alert.Location,
+			Timestamp:        alert.Timestamp,
+			Snippet:          alert.Snippet,
+			ReportID:         reportRecord.ID,
+			VideoID:          state.Video.ID,
+			OriginalFilename: state.Video.OriginalFilename,
+			RiskLevel:        reportRecord.RiskLevel,
+			SentimentScore:   reportRecord.SentimentScore,
+			CreatedAt:        alert.CreatedAt,
+		}
+		if err := wp.webhooks.Enqueue(tx, notice.RecipientID, models.WebhookEventAlertTriggered, data); err != nil {
+			return err
+		}
+	}
+
+	if wp.notifier == nil {
+		return nil
+	}
+	msg, err := notifier.RenderAlert(notifier.AlertData{
+		AlertID:        alert.ID,
+		Source:         alert.Source,
+		Severity:       alert.Severity,
+		RuleName:       notice.RuleName,
+		WatchlistName:  notice.WatchlistName,
+		Term:           alert.Term,
+		Location:       alert.Location,
+		Timestamp:      alert.Timestamp,
+		Occurrences:    alert.Occurrences,
+		Snippet:        alert.Snippet,
+		VideoID:        state.Video.ID,
+		Filename:       state.Video.OriginalFilename,
+		RiskLevel:      reportRecord.RiskLevel,
+		SentimentScore: reportRecord.SentimentScore,
+		Link:           wp.notifier.ReportLink(state.Video.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return wp.notifier.Enqueue(tx, notice.RecipientID, channels, msg)
+}
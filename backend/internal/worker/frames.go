@@ -0,0 +1,198 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"opinion-monitor/internal/fingerprint"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/pkg/video"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// duplicateSimilarity is how alike two frame texts must be (0-1) to count as
+// the same caption.
+const duplicateSimilarity = 0.85
+
+// frameText is the text recognized on one sampled frame.
+type frameText struct {
+	Timestamp float64 `json:"timestamp"`
+	Path      string  `json:"path"`
+	Text      string  `json:"text"`
+	Duplicate bool    `json:"duplicate"` // repeats an earlier frame's text
+}
+
+// framesDir is where the sampled frames of a video are written.
+func framesDir(videoRecord *models.Video) string {
+	return filepath.Join(filepath.Dir(videoRecord.FilePath), fmt.Sprintf("frames_%d", videoRecord.ID))
+}
+
+func (wp *WorkerPool) extractFrames(state *pipelineState) (stageOutput, error) {
+	videoRecord := state.Video
+
+	duration := videoRecord.Duration
+	if duration <= 0 {
+		duration, _ = wp.processor.GetVideoDuration(videoRecord.FilePath)
+	}
+
+	frames, err := wp.processor.ExtractFrames(videoRecord.FilePath, framesDir(&videoRecord), duration, wp.frameSampling)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract frames: %w", err)
+	}
+
+	state.Frames = frames
+	return stageOutput{"frames": frames}, nil
+}
+
+// fingerprint hashes frames sampled across the video, so that re-uploads
+// and edited copies of it can be found.
+func (wp *WorkerPool) fingerprint(state *pipelineState) (stageOutput, error) {
+	videoRecord := state.Video
+
+	duration := videoRecord.Duration
+	if duration <= 0 {
+		duration, _ = wp.processor.GetVideoDuration(videoRecord.FilePath)
+	}
+
+	hashes, err := wp.processor.FrameHashes(videoRecord.FilePath, duration, wp.cfg.Fingerprints.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint video: %w", err)
+	}
+
+	if err := wp.db.Transaction(func(tx *gorm.DB) error {
+		return fingerprint.Save(tx, videoRecord.ID, hashes, wp.cfg.Fingerprints.MaxDistance)
+	}); err != nil {
+		return nil, err
+	}
+
+	return stageOutput{"fingerprint_frames": len(hashes)}, nil
+}
+
+func (wp *WorkerPool) extractFrameText(state *pipelineState) (stageOutput, error) {
+	// Fall back to the cover when no frames could be sampled
+	frames := state.Frames
+	if len(frames) == 0 {
+		frames = []video.Frame{{Path: state.CoverPath, Timestamp: coverTimestamp}}
+	}
+
+	texts := make([]frameText, 0, len(frames))
+	for _, frame := range frames {
+		text, err := state.aiClient.ExtractTextFromImage(frame.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from frame at %.2fs: %w", frame.Timestamp, err)
+		}
+		texts = append(texts, frameText{
+			Timestamp: frame.Timestamp,
+			Path:      frame.Path,
+			Text:      strings.TrimSpace(text),
+		})
+	}
+
+	markDuplicateTexts(texts)
+	coverText := combineFrameTexts(texts)
+
+	log.Printf("Extracted text from %d frames of job %d (%d characters)", len(texts), state.JobID, utf8.RuneCountInString(coverText))
+
+	state.FrameTexts = texts
+	state.CoverText = coverText
+	return stageOutput{"frame_texts": texts, "cover_text": coverText}, nil
+}
+
+// markDuplicateTexts flags frames whose text repeats an earlier frame, which
+// is typical for captions and watermarks that stay on screen.
+func markDuplicateTexts(texts []frameText) {
+	var seen []string
+	for i := range texts {
+		normalized := normalizeText(texts[i].Text)
+		if normalized == "" {
+			texts[i].Duplicate = true
+			continue
+		}
+
+		for _, previous := range seen {
+			if textSimilarity(normalized, previous) >= duplicateSimilarity {
+				texts[i].Duplicate = true
+				break
+			}
+		}
+
+		if !texts[i].Duplicate {
+			seen = append(seen, normalized)
+		}
+	}
+}
+
+// combineFrameTexts joins the distinct frame texts, each prefixed with its timestamp.
+func combineFrameTexts(texts []frameText) string {
+	var b strings.Builder
+	for _, t := range texts {
+		if t.Duplicate {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%s] %s", formatTimestamp(t.Timestamp), t.Text)
+	}
+	return b.String()
+}
+
+// normalizeText drops whitespace and case, which OCR often gets inconsistent
+// between frames showing the same caption.
+func normalizeText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), ""))
+}
+
+// textSimilarity is the Dice coefficient of the character bigrams of a and b.
+func textSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	bigramsA := bigrams(a)
+	bigramsB := bigrams(b)
+	if len(bigramsA) == 0 || len(bigramsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(bigramsA))
+	for _, bg := range bigramsA {
+		counts[bg]++
+	}
+
+	shared := 0
+	for _, bg := range bigramsB {
+		if counts[bg] > 0 {
+			counts[bg]--
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(len(bigramsA)+len(bigramsB))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return nil
+	}
+
+	result := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		result = append(result, string(runes[i:i+2]))
+	}
+	return result
+}
+
+// formatTimestamp renders seconds as mm:ss, or h:mm:ss for long videos.
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	h, m, s := total/3600, total%3600/60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,542 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"opinion-monitor/internal/events"
+	"opinion-monitor/internal/models"
+	"opinion-monitor/pkg/ai"
+	"opinion-monitor/pkg/video"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Pipeline stage names, in execution order.
+const (
+	StageExtractCover = "extract_cover"
+	StageExtractFrame = "extract_frames"
+	StageFingerprint  = "fingerprint"
+	StageExtractAudio = "extract_audio"
+	StageTranscribe   = "transcribe"
+	StageOCR          = "ocr"
+	StageAnalyze      = "analyze_sentiment"
+	StageSaveReport   = "save_report"
+)
+
+// PipelineVersion is recorded on every report. Bump it whenever the stages or
+// the way their results are combined change.
+const PipelineVersion = "v3"
+
+// coverTimestamp is where the cover frame is taken, in seconds.
+const coverTimestamp = 1.0
+
+// errSkipStage is returned by optional stages that have nothing to do.
+var errSkipStage = errors.New("stage not applicable")
+
+// pipelineState is what the stages hand to each other. A stage returns the
+// fields it produced using the same JSON keys as this struct, so that a
+// checkpointed output can be decoded straight back into the state on resume.
+type pipelineState struct {
+	JobID              uint                `json:"-"`
+	Video              models.Video        `json:"-"`
+	CoverPath          string              `json:"cover_path,omitempty"`
+	Frames             []video.Frame       `json:"frames,omitempty"`
+	FrameTexts         []frameText         `json:"frame_texts,omitempty"`
+	AudioPath          string              `json:"audio_path,omitempty"`
+	TranscriptText     string              `json:"transcript_text,omitempty"`
+	TranscriptLanguage string              `json:"transcript_language,omitempty"`
+	CoverText          string              `json:"cover_text,omitempty"`
+	Sentiment          *ai.SentimentReport `json:"sentiment,omitempty"`
+	// What produced Sentiment; it may come from another job when reused
+	ModelVision    string  `json:"model_vision,omitempty"`
+	ModelChat      string  `json:"model_chat,omitempty"`
+	PromptVersion  string  `json:"prompt_version,omitempty"`
+	ReportID       uint    `json:"report_id,omitempty"`
+	ProcessingTime float64 `json:"processing_time,omitempty"`
+
+	ctx      context.Context // cancelled when the job's lease is lost
+	aiClient ai.Client       // honours the job's model and prompt overrides
+	prompts  *ai.Prompts     // recorded on the report
+}
+
+type stageOutput map[string]interface{}
+
+type stage struct {
+	name     string
+	optional bool // a failure is recorded and the pipeline carries on
+	run      func(state *pipelineState) (stageOutput, error)
+}
+
+func (wp *WorkerPool) stages() []stage {
+	return []stage{
+		{name: StageExtractCover, run: wp.extractCover},
+		{name: StageExtractFrame, optional: true, run: wp.extractFrames},
+		{name: StageFingerprint, optional: true, run: wp.fingerprint},
+		{name: StageExtractAudio, optional: true, run: wp.extractAudio},
+		{name: StageTranscribe, optional: true, run: wp.transcribe},
+		{name: StageOCR, run: wp.extractFrameText},
+		{name: StageAnalyze, run: wp.analyzeSentiment},
+		{name: StageSaveReport, run: wp.saveReport},
+	}
+}
+
+// runPipeline runs the stages of a job in order. Stages checkpointed as
+// completed by an earlier attempt are not run again; their saved output is
+// restored into state instead. Skipped stages are run again, as their
+// failure may have been passing, until the analysis using their output is
+// checkpointed.
+func (wp *WorkerPool) runPipeline(state *pipelineState) error {
+	var existing []models.JobStage
+	if err := wp.db.Where("job_id = ?", state.JobID).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to load stage checkpoints: %w", err)
+	}
+
+	checkpoints := make(map[string]*models.JobStage, len(existing))
+	for i := range existing {
+		checkpoints[existing[i].Name] = &existing[i]
+	}
+
+	analyzed := false
+	if checkpoint, ok := checkpoints[StageAnalyze]; ok && checkpoint.Status == models.StageStatusCompleted {
+		analyzed = true
+	}
+
+	for position, st := range wp.stages() {
+		if state.ctx.Err() != nil {
+			return ErrLeaseLost
+		}
+
+		checkpoint, ok := checkpoints[st.name]
+		if !ok {
+			checkpoint = &models.JobStage{
+				JobID:    state.JobID,
+				Name:     st.name,
+				Position: position,
+				Status:   models.StageStatusPending,
+			}
+			if err := wp.db.Create(checkpoint).Error; err != nil {
+				return fmt.Errorf("failed to create stage %s: %w", st.name, err)
+			}
+		}
+
+		if checkpoint.Status == models.StageStatusCompleted || (checkpoint.Status == models.StageStatusSkipped && analyzed) {
+			if checkpoint.Output != "" {
+				if err := json.Unmarshal([]byte(checkpoint.Output), state); err != nil {
+					return fmt.Errorf("failed to restore stage %s: %w", st.name, err)
+				}
+			}
+			continue
+		}
+
+		if err := wp.runStage(st, checkpoint, state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runStage runs a single stage and persists its status, output and timing.
+func (wp *WorkerPool) runStage(st stage, checkpoint *models.JobStage, state *pipelineState) error {
+	startedAt := time.Now()
+
+	wp.db.Model(&models.Job{}).Where("id = ?", state.JobID).Update("current_stage", st.name)
+	if err := wp.db.Model(checkpoint).Updates(map[string]interface{}{
+		"status":      models.StageStatusRunning,
+		"started_at":  startedAt,
+		"finished_at": nil,
+		"error":       "",
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update stage %s: %w", st.name, err)
+	}
+	wp.publishStage(events.JobStageStarted, checkpoint.Position, st.name, models.StageStatusRunning, state, "")
+
+	output, runErr := st.run(state)
+
+	// Another worker may be running this stage now; do not overwrite it
+	if state.ctx.Err() != nil || errors.Is(runErr, ErrLeaseLost) {
+		return ErrLeaseLost
+	}
+
+	finishedAt := time.Now()
+	updates := map[string]interface{}{
+		"finished_at": finishedAt,
+		"duration":    finishedAt.Sub(startedAt).Seconds(),
+	}
+
+	var status models.StageStatus
+	var errorMsg string
+	switch {
+	case runErr == nil:
+		outputJSON, err := json.Marshal(output)
+		if err != nil {
+			return fmt.Errorf("failed to encode output of stage %s: %w", st.name, err)
+		}
+		status = models.StageStatusCompleted
+		updates["output"] = string(outputJSON)
+	case st.optional:
+		if !errors.Is(runErr, errSkipStage) {
+			log.Printf("Warning: stage %s of job %d failed, continuing: %v", st.name, state.JobID, runErr)
+		}
+		status = models.StageStatusSkipped
+		errorMsg = runErr.Error()
+	default:
+		status = models.StageStatusFailed
+		errorMsg = runErr.Error()
+	}
+	updates["status"] = status
+	if errorMsg != "" {
+		updates["error"] = errorMsg
+	}
+
+	if err := wp.db.Model(checkpoint).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update stage %s: %w", st.name, err)
+	}
+	wp.publishStage(events.JobStageFinished, checkpoint.Position, st.name, status, state, errorMsg)
+
+	if runErr != nil && !st.optional {
+		return fmt.Errorf("stage %s: %w", st.name, runErr)
+	}
+	return nil
+}
+
+// publishStage reports a stage transition. Progress is the share of stages
+// done, counting the stage itself once it has finished.
+func (wp *WorkerPool) publishStage(eventType events.Type, position int, name string, status models.StageStatus, state *pipelineState, errorMsg string) {
+	done := position
+	if eventType == events.JobStageFinished {
+		done++
+	}
+
+	wp.publish(events.Event{
+		Type:        eventType,
+		UserID:      state.Video.UserID,
+		JobID:       state.JobID,
+		VideoID:     state.Video.ID,
+		Stage:       name,
+		StageStatus: string(status),
+		Progress:    done * 100 / len(wp.stages()),
+		Error:       errorMsg,
+	})
+}
+
+// coverPath is where the cover frame of a video is written.
+func coverPath(videoRecord *models.Video) string {
+	return filepath.Join(filepath.Dir(videoRecord.FilePath), fmt.Sprintf("cover_%d.jpg", videoRecord.ID))
+}
+
+func (wp *WorkerPool) extractCover(state *pipelineState) (stageOutput, error) {
+	videoRecord := state.Video
+	coverPath := coverPath(&videoRecord)
+
+	if err := wp.processor.ExtractCover(videoRecord.FilePath, coverPath, coverTimestamp); err != nil {
+		// Only a file ffprobe cannot read will never work; anything else
+		// (a full disk, ffmpeg killed) may on the next attempt
+		if _, probeErr := wp.processor.Probe(videoRecord.FilePath); errors.Is(probeErr, video.ErrInvalidVideo) {
+			return nil, Permanent(fmt.Errorf("failed to extract cover: %w", probeErr))
+		}
+		return nil, fmt.Errorf("failed to extract cover: %w", err)
+	}
+
+	// Update video with cover path, and the duration if it is not known yet
+	updates := map[string]interface{}{"cover_path": coverPath}
+	if videoRecord.Duration <= 0 {
+		if duration, err := wp.processor.GetVideoDuration(videoRecord.FilePath); err == nil {
+			updates["duration"] = duration
+			state.Video.Duration = duration
+		}
+	}
+	if err := wp.db.Model(&videoRecord).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update cover path: %w", err)
+	}
+
+	state.CoverPath = coverPath
+	return stageOutput{"cover_path": coverPath}, nil
+}
+
+func (wp *WorkerPool) extractAudio(state *pipelineState) (stageOutput, error) {
+	videoRecord := state.Video
+	audioFilename := fmt.Sprintf("audio_%d.wav", videoRecord.ID)
+	audioPath := filepath.Join(filepath.Dir(videoRecord.FilePath), audioFilename)
+
+	if err := wp.processor.ExtractAudio(videoRecord.FilePath, audioPath); err != nil {
+		return nil, fmt.Errorf("failed to extract audio: %w", err)
+	}
+
+	// Update video with audio path
+	if err := wp.db.Model(&videoRecord).Update("audio_path", audioPath).Error; err != nil {
+		return nil, fmt.Errorf("failed to update audio path: %w", err)
+	}
+
+	state.AudioPath = audioPath
+	return stageOutput{"audio_path": audioPath}, nil
+}
+
+func (wp *WorkerPool) transcribe(state *pipelineState) (stageOutput, error) {
+	// Nothing to transcribe when audio extraction was skipped
+	if wp.transcriber == nil || state.AudioPath == "" {
+		return nil, errSkipStage
+	}
+
+	transcript, err := wp.transcribeAudio(state.Video.ID, state.AudioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	log.Printf("Transcribed audio of job %d (%s, %d segments, %d characters)", state.JobID, transcript.Language, len(transcript.Segments), utf8.RuneCountInString(transcript.Text))
+
+	// Replace the video's transcript and its segments
+	err = wp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&state.Video).Updates(map[string]interface{}{
+			"transcript_text":     transcript.Text,
+			"transcript_language": transcript.Language,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("video_id = ?", state.Video.ID).Delete(&models.TranscriptSegment{}).Error; err != nil {
+			return err
+		}
+		if len(transcript.Segments) == 0 {
+			return nil
+		}
+
+		segments := make([]models.TranscriptSegment, 0, len(transcript.Segments))
+		for i, seg := range transcript.Segments {
+			segments = append(segments, models.TranscriptSegment{
+				VideoID:  state.Video.ID,
+				Position: i,
+				Start:    seg.Start,
+				End:      seg.End,
+				Text:     seg.Text,
+			})
+		}
+		return tx.Create(&segments).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save transcript: %w", err)
+	}
+
+	state.TranscriptText = transcript.Text
+	state.TranscriptLanguage = transcript.Language
+	return stageOutput{
+		"transcript_text":     transcript.Text,
+		"transcript_language": transcript.Language,
+	}, nil
+}
+
+func (wp *WorkerPool) analyzeSentiment(state *pipelineState) (stageOutput, error) {
+	// Combine cover text and transcript for comprehensive analysis
+	var combinedText strings.Builder
+	combinedText.WriteString("画面文字（按时间顺序）：\n")
+	combinedText.WriteString(state.CoverText)
+	if state.TranscriptText != "" {
+		combinedText.WriteString("\n\n音频转录文字：\n")
+		combinedText.WriteString(state.TranscriptText)
+	}
+
+	report, err := state.aiClient.AnalyzeSentiment(combinedText.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze sentiment: %w", err)
+	}
+
+	state.Sentiment = report
+	state.ModelVision, state.ModelChat = state.aiClient.Models()
+	state.PromptVersion = state.prompts.Version
+	return stageOutput{
+		"sentiment":      report,
+		"model_vision":   state.ModelVision,
+		"model_chat":     state.ModelChat,
+		"prompt_version": state.PromptVersion,
+	}, nil
+}
+
+func (wp *WorkerPool) saveReport(state *pipelineState) (stageOutput, error) {
+	report := state.Sentiment
+	if report == nil {
+		return nil, fmt.Errorf("no sentiment analysis to save")
+	}
+
+	// Processing time covers every stage across all attempts, not just this run
+	var processingTime float64
+	wp.db.Model(&models.JobStage{}).
+		Where("job_id = ?", state.JobID).
+		Select("COALESCE(SUM(duration), 0)").
+		Scan(&processingTime)
+
+	// Convert arrays to JSON strings
+	keyTopicsJSON, _ := json.Marshal(report.KeyTopics)
+	recommendationsJSON, _ := json.Marshal(report.Recommendations)
+	var evidenceJSON []byte
+	if len(report.Evidence) > 0 {
+		evidenceJSON, _ = json.Marshal(report.Evidence)
+	}
+
+	// Analyses checkpointed before the models were recorded ran with this job's
+	modelVision, modelChat, promptVersion := state.ModelVision, state.ModelChat, state.PromptVersion
+	if modelChat == "" {
+		modelVision, modelChat = state.aiClient.Models()
+		promptVersion = state.prompts.Version
+	}
+
+	reportRecord := models.Report{
+		VideoID:          state.Video.ID,
+		CoverText:        state.CoverText,
+		TranscriptText:   state.TranscriptText,
+		SentimentScore:   report.SentimentScore,
+		SentimentLabel:   report.SentimentLabel,
+		KeyTopics:        string(keyTopicsJSON),
+		RiskLevel:        report.RiskLevel,
+		DetailedAnalysis: report.DetailedAnalysis,
+		Recommendations:  string(recommendationsJSON),
+		Evidence:         string(evidenceJSON),
+		ProcessingTime:   processingTime,
+		IsCurrent:        true,
+		ModelVision:      modelVision,
+		ModelChat:        modelChat,
+		PromptVersion:    promptVersion,
+		PipelineVersion:  PipelineVersion,
+	}
+
+	// Every run adds a new version and makes it the current one; older
+	// versions are kept for comparison.
+	var output stageOutput
+	err := wp.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Unscoped().Model(&models.Report{}).
+			Where("video_id = ?", state.Video.ID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		reportRecord.Version = latest + 1
+
+		if err := tx.Model(&models.Report{}).
+			Where("video_id = ? AND is_current = ?", state.Video.ID, true).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&reportRecord).Error; err != nil {
+			return err
+		}
+
+		// Keep the per-frame text next to the report
+		if len(state.FrameTexts) > 0 {
+			frames := make([]models.ReportFrame, 0, len(state.FrameTexts))
+			for _, t := range state.FrameTexts {
+				frames = append(frames, models.ReportFrame{
+					ReportID:  reportRecord.ID,
+					Timestamp: t.Timestamp,
+					ImagePath: t.Path,
+					Text:      t.Text,
+					Duplicate: t.Duplicate,
+				})
+			}
+			if err := tx.Create(&frames).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := wp.raiseWatchlistAlerts(tx, state, &reportRecord); err != nil {
+			return err
+		}
+		if err := wp.evaluateAlertRules(tx, state, &reportRecord); err != nil {
+			return err
+		}
+
+		if err := wp.enqueueReportWebhooks(tx, state, &reportRecord); err != nil {
+			return err
+		}
+
+		// Checkpoint the stage with the report, so a crash or retry after the
+		// commit cannot add another version and raise its alerts again
+		output = stageOutput{"report_id": reportRecord.ID, "processing_time": processingTime}
+		outputJSON, err := json.Marshal(output)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.JobStage{}).
+			Where("job_id = ? AND name = ? AND status = ?", state.JobID, StageSaveReport, models.StageStatusRunning).
+			Updates(map[string]interface{}{
+				"status":      models.StageStatusCompleted,
+				"output":      string(outputJSON),
+				"finished_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another run of the job saved the report first
+			return ErrLeaseLost
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+	if wp.webhooks != nil {
+		wp.webhooks.Notify()
+	}
+	if wp.notifier != nil {
+		wp.notifier.Notify()
+	}
+
+	state.ReportID = reportRecord.ID
+	state.ProcessingTime = processingTime
+	return output, nil
+}
+
+// reportWebhookData is the data of report webhook events.
+type reportWebhookData struct {
+	ReportID         uint      `json:"report_id"`
+	VideoID          uint      `json:"video_id"`
+	JobID            uint      `json:"job_id"`
+	OriginalFilename string    `json:"original_filename"`
+	Version          int       `json:"version"`
+	SentimentScore   float64   `json:"sentiment_score"`
+	SentimentLabel   string    `json:"sentiment_label"`
+	RiskLevel        string    `json:"risk_level"`
+	KeyTopics        []string  `json:"key_topics"`
+	DetailedAnalysis string    `json:"detailed_analysis"`
+	Recommendations  []string  `json:"recommendations"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// enqueueReportWebhooks queues the webhook deliveries announcing a new report
+// in the transaction that saves it.
+func (wp *WorkerPool) enqueueReportWebhooks(tx *gorm.DB, state *pipelineState, reportRecord *models.Report) error {
+	if wp.webhooks == nil {
+		return nil
+	}
+
+	data := reportWebhookData{
+		ReportID:         reportRecord.ID,
+		VideoID:          state.Video.ID,
+		JobID:            state.JobID,
+		OriginalFilename: state.Video.OriginalFilename,
+		Version:          reportRecord.Version,
+		SentimentScore:   state.Sentiment.SentimentScore,
+		SentimentLabel:   state.Sentiment.SentimentLabel,
+		RiskLevel:        state.Sentiment.RiskLevel,
+		KeyTopics:        state.Sentiment.KeyTopics,
+		DetailedAnalysis: state.Sentiment.DetailedAnalysis,
+		Recommendations:  state.Sentiment.Recommendations,
+		CreatedAt:        reportRecord.CreatedAt,
+	}
+
+	if err := wp.webhooks.Enqueue(tx, state.Video.UserID, models.WebhookEventReportCompleted, data); err != nil {
+		return err
+	}
+	if data.RiskLevel == "high" {
+		return wp.webhooks.Enqueue(tx, state.Video.UserID, models.WebhookEventReportHighRisk, data)
+	}
+	return nil
+}
@@ -1,28 +1,311 @@
 package worker
 
 import (
-	"sync"
+	"errors"
+	"log"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLeaseDuration = 10 * time.Minute
+	defaultPollInterval  = 2 * time.Second
 )
 
+// ErrLeaseLost is returned when a worker no longer owns the job it is processing,
+// usually because its lease expired and another worker claimed the job.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// ErrNotRequeueable is returned when requeueing a job that is still pending,
+// processing or already completed.
+var ErrNotRequeueable = errors.New("job is not dead-lettered or failed")
+
+// ErrJobActive is returned when reanalyzing a video whose job is still
+// pending or processing.
+var ErrJobActive = errors.New("job is still pending or processing")
+
+// JobQueue hands out jobs stored in the jobs table. Workers claim a job by
+// taking a time-limited lease on its row; if a worker dies, the lease expires
+// and the job becomes claimable again, so nothing is lost across restarts.
 type JobQueue struct {
-	jobs chan uint // video IDs
-	mu   sync.Mutex
+	db            *gorm.DB
+	leaseDuration time.Duration
+	pollInterval  time.Duration
+	notify        chan struct{}
 }
 
-func NewJobQueue() *JobQueue {
+func NewJobQueue(db *gorm.DB, cfg *config.Config) *JobQueue {
+	leaseDuration, err := time.ParseDuration(cfg.Worker.LeaseDuration)
+	if err != nil || leaseDuration <= 0 {
+		leaseDuration = defaultLeaseDuration
+	}
+
+	pollInterval, err := time.ParseDuration(cfg.Worker.PollInterval)
+	if err != nil || pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
 	return &JobQueue{
-		jobs: make(chan uint, 100), // Buffer for 100 jobs
+		db:            db,
+		leaseDuration: leaseDuration,
+		pollInterval:  pollInterval,
+		notify:        make(chan struct{}, 1),
 	}
 }
 
-func (q *JobQueue) Push(videoID uint) {
-	q.jobs <- videoID
+// Notify wakes up an idle worker to look for new jobs. It never blocks.
+func (q *JobQueue) Notify() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until Notify is called or the poll interval elapses.
+func (q *JobQueue) Wait() {
+	timer := time.NewTimer(q.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-q.notify:
+	case <-timer.C:
+	}
 }
 
-func (q *JobQueue) Pop() uint {
-	return <-q.jobs
+// Claim leases the oldest runnable job to owner. A job is runnable when it is
+// pending and its backoff has elapsed, or when it is processing but its lease
+// has expired. Claim returns
+// nil when there is nothing to do.
+func (q *JobQueue) Claim(owner string) (*models.Job, error) {
+	for {
+		now := time.Now()
+
+		var job models.Job
+		err := q.runnable(now).Order("created_at ASC").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		leaseExpiresAt := now.Add(q.leaseDuration)
+		result := q.runnable(now).
+			Where("id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"status":           models.JobStatusProcessing,
+				"lease_owner":      owner,
+				"lease_expires_at": leaseExpiresAt,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		// Another worker won the race for this row; try the next one
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		job.Status = models.JobStatusProcessing
+		job.LeaseOwner = owner
+		job.LeaseExpiresAt = &leaseExpiresAt
+		return &job, nil
+	}
+}
+
+// Extend renews the lease on a job held by owner.
+func (q *JobQueue) Extend(jobID uint, owner string) error {
+	result := q.db.Model(&models.Job{}).
+		Where("id = ? AND lease_owner = ? AND status = ?", jobID, owner, models.JobStatusProcessing).
+		Update("lease_expires_at", time.Now().Add(q.leaseDuration))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// KeepAlive renews the lease on a job in the background until the returned
+// function is called. If the lease is lost, lost is called so the worker
+// stops processing a job that another worker may now hold.
+func (q *JobQueue) KeepAlive(jobID uint, owner string, lost func()) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(q.leaseDuration / 3)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := q.Extend(jobID, owner)
+				if errors.Is(err, ErrLeaseLost) {
+					log.Printf("Warning: lost the lease on job %d, stopping", jobID)
+					lost()
+					return
+				}
+				if err != nil {
+					log.Printf("Warning: failed to extend lease on job %d: %v", jobID, err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// Requeue makes a job immediately runnable again with a fresh retry budget.
+// Only jobs that are dead-lettered or failed can be requeued.
+func (q *JobQueue) Requeue(jobID uint) error {
+	var job models.Job
+	if err := q.db.First(&job, jobID).Error; err != nil {
+		return err
+	}
+
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Job{}).
+			Where("id = ? AND status IN ?", jobID, []models.JobStatus{models.JobStatusDead, models.JobStatusFailed}).
+			Updates(map[string]interface{}{
+				"status":           models.JobStatusPending,
+				"retry_count":      0,
+				"available_at":     nil,
+				"lease_owner":      "",
+				"lease_expires_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotRequeueable
+		}
+
+		return tx.Model(&models.Video{}).
+			Where("id = ?", job.VideoID).
+			Update("status", models.StatusPending).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	q.Notify()
+	return nil
+}
+
+// AnalysisOverrides changes how a job is analyzed. Empty fields fall back to
+// the configured defaults.
+type AnalysisOverrides struct {
+	ModelVision   string
+	ModelChat     string
+	PromptVersion string
+}
+
+// analysisStages are rerun on reanalysis; cover, audio and transcript do not
+// depend on the model or prompt and are reused from the previous run.
+var analysisStages = []string{StageOCR, StageAnalyze, StageSaveReport}
+
+// Reanalyze queues a finished video for another analysis run. The current
+// report stays current until the new run saves the next version.
+func (q *JobQueue) Reanalyze(videoID uint, overrides AnalysisOverrides) error {
+	var job models.Job
+	if err := q.db.Where("video_id = ?", videoID).First(&job).Error; err != nil {
+		return err
+	}
+
+	if job.Status == models.JobStatusPending || job.Status == models.JobStatusProcessing {
+		return ErrJobActive
+	}
+
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Job{}).
+			Where("id = ? AND status NOT IN ?", job.ID, []models.JobStatus{models.JobStatusPending, models.JobStatusProcessing}).
+			Updates(map[string]interface{}{
+				"status":           models.JobStatusPending,
+				"retry_count":      0,
+				"error_message":    "",
+				"current_stage":    "",
+				"model_vision":     overrides.ModelVision,
+				"model_chat":       overrides.ModelChat,
+				"prompt_version":   overrides.PromptVersion,
+				"available_at":     nil,
+				"lease_owner":      "",
+				"lease_expires_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrJobActive
+		}
+
+		if err := tx.Where("job_id = ? AND name IN ?", job.ID, analysisStages).Delete(&models.JobStage{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Video{}).
+			Where("id = ?", videoID).
+			Update("status", models.StatusPending).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	q.Notify()
+	return nil
+}
+
+// Recover puts processing jobs whose lease has expired (or that predate
+// leasing) back into the pending state. It is meant to run once at startup.
+func (q *JobQueue) Recover() error {
+	now := time.Now()
+
+	var videoIDs []uint
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Job{}).
+			Where("status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)", models.JobStatusProcessing, now).
+			Pluck("video_id", &videoIDs).Error; err != nil {
+			return err
+		}
+		if len(videoIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Job{}).
+			Where("video_id IN ? AND status = ?", videoIDs, models.JobStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":           models.JobStatusPending,
+				"lease_owner":      "",
+				"lease_expires_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Video{}).
+			Where("id IN ?", videoIDs).
+			Update("status", models.StatusPending).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var pending int64
+	if err := q.db.Model(&models.Job{}).Where("status = ?", models.JobStatusPending).Count(&pending).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Job queue recovered %d stale jobs, %d jobs pending", len(videoIDs), pending)
+	q.Notify()
+	return nil
 }
 
-func (q *JobQueue) Jobs() <-chan uint {
-	return q.jobs
+// runnable scopes a query to jobs that may be claimed at the given time.
+func (q *JobQueue) runnable(now time.Time) *gorm.DB {
+	return q.db.Model(&models.Job{}).
+		Where("((status = ? AND (available_at IS NULL OR available_at <= ?)) OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)))",
+			models.JobStatusPending, now, models.JobStatusProcessing, now)
 }
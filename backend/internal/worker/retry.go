@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"errors"
+	"opinion-monitor/internal/backoff"
+	"opinion-monitor/internal/config"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 30 * time.Second
+	defaultMaxBackoff     = 30 * time.Minute
+)
+
+// permanentError marks a failure that will not go away by retrying,
+// e.g. a deleted video or a file ffmpeg cannot decode.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the retry policy sends the job straight to the dead-letter state.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryPolicy decides whether and when a failed job is attempted again.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	FatalErrors    []string // substrings of error messages that are never retried
+}
+
+func NewRetryPolicy(cfg config.RetryConfig) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		FatalErrors:    cfg.FatalErrors,
+	}
+
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = defaultMaxAttempts
+	}
+	if d, err := time.ParseDuration(cfg.InitialBackoff); err == nil && d > 0 {
+		policy.InitialBackoff = d
+	}
+	if d, err := time.ParseDuration(cfg.MaxBackoff); err == nil && d > 0 {
+		policy.MaxBackoff = d
+	}
+
+	return policy
+}
+
+// Retryable reports whether err is worth another attempt.
+func (p RetryPolicy) Retryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	msg := err.Error()
+	for _, pattern := range p.FatalErrors {
+		if pattern != "" && strings.Contains(msg, pattern) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ShouldRetry reports whether a job that just failed its attempt-th run
+// (starting at 1) should be scheduled again.
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	return attempt < p.MaxAttempts && p.Retryable(err)
+}
+
+// Backoff returns the delay before the next run after the attempt-th failure.
+// The delay doubles on every attempt, is capped at MaxBackoff and has up to
+// 20% jitter so that jobs failing together do not retry together.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	return backoff.Policy{Initial: p.InitialBackoff, Max: p.MaxBackoff}.Delay(attempt)
+}
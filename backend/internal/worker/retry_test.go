@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"opinion-monitor/internal/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RetryConfig
+		want RetryPolicy
+	}{
+		{
+			"defaults",
+			config.RetryConfig{},
+			RetryPolicy{MaxAttempts: defaultMaxAttempts, InitialBackoff: defaultInitialBackoff, MaxBackoff: defaultMaxBackoff},
+		},
+		{
+			"configured",
+			config.RetryConfig{MaxAttempts: 5, InitialBackoff: "10s", MaxBackoff: "1h", FatalErrors: []string{"quota"}},
+			RetryPolicy{MaxAttempts: 5, InitialBackoff: 10 * time.Second, MaxBackoff: time.Hour, FatalErrors: []string{"quota"}},
+		},
+		{
+			"invalid values fall back",
+			config.RetryConfig{MaxAttempts: -1, InitialBackoff: "0s", MaxBackoff: "later"},
+			RetryPolicy{MaxAttempts: defaultMaxAttempts, InitialBackoff: defaultInitialBackoff, MaxBackoff: defaultMaxBackoff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRetryPolicy(tt.cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("NewRetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration // before jitter
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute}, // 8m capped
+		{6, 5 * time.Minute},
+		{1000, 5 * time.Minute}, // no overflow
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			// Jitter is random; every delay must stay within 20% above the base
+			for i := 0; i < 200; i++ {
+				got := policy.Backoff(tt.attempt)
+				if got < tt.base || got > tt.base+tt.base/5 {
+					t.Fatalf("Backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.base, tt.base+tt.base/5)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffJitterSpreads(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Minute, MaxBackoff: time.Hour}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		seen[policy.Backoff(2)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("Backoff(2) returned the same delay 50 times; jitter is missing")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, FatalErrors: []string{"", "invalid api key", "unsupported codec"}}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"transient", errors.New("connection reset by peer"), true},
+		{"permanent", Permanent(errors.New("video not found")), false},
+		{"wrapped permanent", fmt.Errorf("stage extract_cover: %w", Permanent(errors.New("ffmpeg failed"))), false},
+		{"fatal pattern", errors.New("openai: invalid api key provided"), false},
+		{"fatal pattern when wrapped", fmt.Errorf("stage ocr: %w", errors.New("unsupported codec hevc")), false},
+		{"pattern is case-sensitive", errors.New("Invalid API key"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Retryable(tt.err); got != tt.want {
+				t.Fatalf("Retryable(%q) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	transient := errors.New("timeout")
+
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    bool
+	}{
+		{"first failure", 1, transient, true},
+		{"second failure", 2, transient, true},
+		{"last attempt", 3, transient, false},
+		{"permanent on first failure", 1, Permanent(transient), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.ShouldRetry(tt.attempt, tt.err); got != tt.want {
+				t.Fatalf("ShouldRetry(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermanentNil(t *testing.T) {
+	if err := Permanent(nil); err != nil {
+		t.Fatalf("Permanent(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"opinion-monitor/internal/models"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reusableStages call the transcription and AI models. Their results depend
+// only on the file content, so a copy of a file can take them from the
+// original; cover, frames and the report are still made for the copy.
+var reusableStages = []string{StageExtractAudio, StageTranscribe, StageOCR, StageAnalyze}
+
+// ReuseAnalysis checkpoints the reusable stages of the job jobID of video to
+// with the results of the original video from, together with the transcript
+// they produced, so that running the job only extracts the cover and frames
+// and saves the report. It reports false, changing nothing, when the
+// original has no completed analysis.
+func ReuseAnalysis(tx *gorm.DB, from, to *models.Video, jobID uint) (bool, error) {
+	var stages []models.JobStage
+	if err := tx.Joins("JOIN jobs ON jobs.id = job_stages.job_id").
+		Where("jobs.video_id = ? AND job_stages.name IN ? AND job_stages.status IN ?", from.ID, reusableStages,
+			[]models.StageStatus{models.StageStatusCompleted, models.StageStatusSkipped}).
+		Find(&stages).Error; err != nil {
+		return false, fmt.Errorf("failed to load stages of video %d: %w", from.ID, err)
+	}
+
+	found := make(map[string]bool, len(stages))
+	for _, st := range stages {
+		found[st.Name] = true
+	}
+	for _, name := range reusableStages {
+		if !found[name] {
+			return false, nil
+		}
+	}
+
+	now := time.Now()
+	for _, st := range stages {
+		output := st.Output
+		switch st.Name {
+		case StageExtractAudio:
+			// The audio file is the original's; the copy needs none of its
+			// own since its transcript is reused too
+			output = ""
+		case StageOCR:
+			rewritten, err := rewriteFramePaths(output, from, to)
+			if err != nil {
+				return false, err
+			}
+			output = rewritten
+		case StageAnalyze:
+			recorded, err := recordAnalysisModels(tx, output, from)
+			if err != nil {
+				return false, err
+			}
+			output = recorded
+		}
+
+		// Zero duration, so the report's processing time is this job's own
+		copied := models.JobStage{
+			JobID:      jobID,
+			Name:       st.Name,
+			Position:   st.Position,
+			Status:     st.Status,
+			Output:     output,
+			Error:      st.Error,
+			StartedAt:  &now,
+			FinishedAt: &now,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return false, fmt.Errorf("failed to copy stage %s: %w", st.Name, err)
+		}
+	}
+
+	if err := tx.Model(to).Updates(map[string]interface{}{
+		"transcript_text":     from.TranscriptText,
+		"transcript_language": from.TranscriptLanguage,
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to copy transcript: %w", err)
+	}
+
+	var segments []models.TranscriptSegment
+	if err := tx.Where("video_id = ?", from.ID).Order("position ASC").Find(&segments).Error; err != nil {
+		return false, fmt.Errorf("failed to load transcript segments: %w", err)
+	}
+	if len(segments) > 0 {
+		for i := range segments {
+			segments[i].ID = 0
+			segments[i].VideoID = to.ID
+			segments[i].CreatedAt = time.Time{}
+		}
+		if err := tx.Create(&segments).Error; err != nil {
+			return false, fmt.Errorf("failed to copy transcript segments: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// rewriteFramePaths points the frame texts of a checkpointed OCR output at
+// the frames and cover the copy extracts itself. Frames are named by their
+// position, so the same sampling yields the same names.
+func rewriteFramePaths(output string, from, to *models.Video) (string, error) {
+	var ocr map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &ocr); err != nil {
+		return "", fmt.Errorf("failed to decode stage %s: %w", StageOCR, err)
+	}
+
+	raw, ok := ocr["frame_texts"]
+	if !ok {
+		return output, nil
+	}
+	var texts []frameText
+	if err := json.Unmarshal(raw, &texts); err != nil {
+		return "", fmt.Errorf("failed to decode stage %s: %w", StageOCR, err)
+	}
+
+	fromDir, toDir := framesDir(from), framesDir(to)
+	for i := range texts {
+		switch {
+		case filepath.Dir(texts[i].Path) == fromDir:
+			texts[i].Path = filepath.Join(toDir, filepath.Base(texts[i].Path))
+		case texts[i].Path == coverPath(from):
+			texts[i].Path = coverPath(to)
+		}
+	}
+
+	raw, err := json.Marshal(texts)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode stage %s: %w", StageOCR, err)
+	}
+	ocr["frame_texts"] = raw
+
+	rewritten, err := json.Marshal(ocr)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode stage %s: %w", StageOCR, err)
+	}
+	return string(rewritten), nil
+}
+
+// recordAnalysisModels makes sure a checkpointed analysis output names the
+// models and prompt version that produced it, so the copy's report does not
+// claim those of its own job. Outputs saved before they were recorded take
+// them from the original's current report.
+func recordAnalysisModels(tx *gorm.DB, output string, from *models.Video) (string, error) {
+	var analysis map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &analysis); err != nil {
+		return "", fmt.Errorf("failed to decode stage %s: %w", StageAnalyze, err)
+	}
+	if _, ok := analysis["model_chat"]; ok {
+		return output, nil
+	}
+
+	var report models.Report
+	if err := tx.Where("video_id = ? AND is_current = ?", from.ID, true).First(&report).Error; err != nil {
+		return "", fmt.Errorf("failed to load report of video %d: %w", from.ID, err)
+	}
+	for key, value := range map[string]string{
+		"model_vision":   report.ModelVision,
+		"model_chat":     report.ModelChat,
+		"prompt_version": report.PromptVersion,
+	} {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode stage %s: %w", StageAnalyze, err)
+		}
+		analysis[key] = raw
+	}
+
+	recorded, err := json.Marshal(analysis)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode stage %s: %w", StageAnalyze, err)
+	}
+	return string(recorded), nil
+}
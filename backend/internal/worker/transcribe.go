@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"opinion-monitor/internal/config"
+	"opinion-monitor/pkg/video"
+	"opinion-monitor/pkg/whisper"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultChunkDuration    = 10 * time.Minute
+	defaultChunkOverlap     = 2 * time.Second
+	defaultChunkConcurrency = 2
+)
+
+// ChunkPolicy controls how long audio is split before transcription.
+type ChunkPolicy struct {
+	Duration    time.Duration // audio longer than this is split
+	Overlap     time.Duration // added to both sides of every chunk
+	Concurrency int           // chunks transcribed at the same time
+}
+
+func NewChunkPolicy(cfg config.WhisperConfig) ChunkPolicy {
+	policy := ChunkPolicy{
+		Duration:    defaultChunkDuration,
+		Overlap:     defaultChunkOverlap,
+		Concurrency: cfg.ChunkConcurrency,
+	}
+
+	if d, err := time.ParseDuration(cfg.ChunkDuration); err == nil && d > 0 {
+		policy.Duration = d
+	}
+	if d, err := time.ParseDuration(cfg.ChunkOverlap); err == nil && d >= 0 {
+		policy.Overlap = d
+	}
+	if policy.Concurrency < 1 {
+		policy.Concurrency = defaultChunkConcurrency
+	}
+
+	return policy
+}
+
+// transcribeAudio transcribes audioPath, splitting it into silence-aligned
+// chunks that are transcribed concurrently when it is longer than the chunk
+// duration.
+func (wp *WorkerPool) transcribeAudio(videoID uint, audioPath string) (*whisper.Transcript, error) {
+	duration, err := wp.processor.GetVideoDuration(audioPath)
+	if err != nil || duration <= wp.chunkPolicy.Duration.Seconds() {
+		return wp.transcriber.TranscribeAudio(audioPath)
+	}
+
+	// Cutting inside silences is best effort; fixed cuts still work
+	silences, err := wp.processor.DetectSilences(audioPath)
+	if err != nil {
+		log.Printf("Warning: silence detection failed for video %d: %v", videoID, err)
+	}
+
+	chunks := video.PlanAudioChunks(duration, wp.chunkPolicy.Duration.Seconds(), wp.chunkPolicy.Overlap.Seconds(), silences)
+	chunkDir := filepath.Join(filepath.Dir(audioPath), fmt.Sprintf("audio_chunks_%d", videoID))
+	defer os.RemoveAll(chunkDir)
+
+	log.Printf("Transcribing %.0fs of audio for video %d in %d chunks", duration, videoID, len(chunks))
+
+	transcripts := make([]*whisper.Transcript, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, wp.chunkPolicy.Concurrency)
+	var wg sync.WaitGroup
+
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := wp.processor.ExtractAudioChunk(audioPath, chunkDir, &chunks[i]); err != nil {
+				errs[i] = fmt.Errorf("failed to extract audio chunk %d: %w", i, err)
+				return
+			}
+			transcript, err := wp.transcriber.TranscribeAudio(chunks[i].Path)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to transcribe audio chunk %d: %w", i, err)
+				return
+			}
+			transcripts[i] = transcript
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stitchTranscripts(chunks, transcripts), nil
+}
+
+// stitchTranscripts merges chunk transcripts into one. Segment times are
+// shifted to the position of their chunk; in the overlaps, a segment is kept
+// by the chunk its midpoint falls into, and a segment repeating the text of
+// the one before it is dropped.
+func stitchTranscripts(chunks []video.AudioChunk, transcripts []*whisper.Transcript) *whisper.Transcript {
+	var segments []whisper.Segment
+	languages := make(map[string]int)
+
+	for i, chunk := range chunks {
+		transcript := transcripts[i]
+		languages[transcript.Language]++
+
+		chunkSegments := transcript.Segments
+		if len(chunkSegments) == 0 && strings.TrimSpace(transcript.Text) != "" {
+			// Backends without segments: treat the chunk as a single one
+			chunkSegments = []whisper.Segment{{Start: 0, End: chunk.End - chunk.Start, Text: transcript.Text}}
+		}
+
+		last := i == len(chunks)-1
+		for _, seg := range chunkSegments {
+			seg.Start += chunk.Start
+			seg.End += chunk.Start
+
+			mid := (seg.Start + seg.End) / 2
+			if mid < chunk.CutStart || mid > chunk.CutEnd || (mid == chunk.CutEnd && !last) {
+				continue
+			}
+
+			if n := len(segments); n > 0 && seg.Start < segments[n-1].End &&
+				textSimilarity(normalizeText(seg.Text), normalizeText(segments[n-1].Text)) >= duplicateSimilarity {
+				continue
+			}
+
+			segments = append(segments, seg)
+		}
+	}
+
+	// The language most chunks were detected as
+	language, best := "", 0
+	for _, transcript := range transcripts {
+		if count := languages[transcript.Language]; transcript.Language != "" && count > best {
+			language, best = transcript.Language, count
+		}
+	}
+
+	texts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		texts = append(texts, strings.TrimSpace(seg.Text))
+	}
+
+	// Languages written without spaces between words
+	separator := " "
+	switch language {
+	case "zh", "ja", "yue":
+		separator = ""
+	}
+
+	return &whisper.Transcript{
+		Text:     strings.Join(texts, separator),
+		Language: language,
+		Segments: segments,
+	}
+}
@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"opinion-monitor/internal/config"
+	"opinion-monitor/pkg/video"
+	"opinion-monitor/pkg/whisper"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStitchTranscripts(t *testing.T) {
+	// 20s of audio cut at 10s with 2s of overlap
+	chunks := []video.AudioChunk{
+		{Index: 0, Start: 0, End: 12, CutStart: 0, CutEnd: 10},
+		{Index: 1, Start: 8, End: 20, CutStart: 10, CutEnd: 20},
+	}
+
+	tests := []struct {
+		name        string
+		transcripts []*whisper.Transcript
+		want        *whisper.Transcript
+	}{
+		{
+			name: "overlap kept by the chunk holding the midpoint",
+			transcripts: []*whisper.Transcript{
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 0, End: 4, Text: "hello there"},
+					{Start: 4, End: 8.5, Text: "how are you"},
+					{Start: 9, End: 11.5, Text: "fine thanks"}, // midpoint 10.25, chunk 1's
+				}},
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 0, End: 0.5, Text: "you"}, // midpoint 8.25, chunk 0's
+					{Start: 1, End: 3.5, Text: "fine thanks"},
+					{Start: 4, End: 8, Text: "goodbye"},
+				}},
+			},
+			want: &whisper.Transcript{
+				Text:     "hello there how are you fine thanks goodbye",
+				Language: "en",
+				Segments: []whisper.Segment{
+					{Start: 0, End: 4, Text: "hello there"},
+					{Start: 4, End: 8.5, Text: "how are you"},
+					{Start: 9, End: 11.5, Text: "fine thanks"},
+					{Start: 12, End: 16, Text: "goodbye"},
+				},
+			},
+		},
+		{
+			name: "midpoint on the cut goes to the later chunk",
+			transcripts: []*whisper.Transcript{
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 2, End: 6, Text: "first"},
+					{Start: 8, End: 12, Text: "on the cut"},
+				}},
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 0, End: 4, Text: "on the cut"},
+				}},
+			},
+			want: &whisper.Transcript{
+				Text:     "first on the cut",
+				Language: "en",
+				Segments: []whisper.Segment{
+					{Start: 2, End: 6, Text: "first"},
+					{Start: 8, End: 12, Text: "on the cut"},
+				},
+			},
+		},
+		{
+			name: "repeated text across the cut is dropped",
+			transcripts: []*whisper.Transcript{
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 9, End: 10.8, Text: "Fine thanks"}, // midpoint 9.9
+				}},
+				{Language: "en", Segments: []whisper.Segment{
+					{Start: 2.2, End: 4, Text: "fine  thanks"}, // 10.2-12, overlaps the one before
+					{Start: 5, End: 7, Text: "bye"},
+				}},
+			},
+			want: &whisper.Transcript{
+				Text:     "Fine thanks bye",
+				Language: "en",
+				Segments: []whisper.Segment{
+					{Start: 9, End: 10.8, Text: "Fine thanks"},
+					{Start: 13, End: 15, Text: "bye"},
+				},
+			},
+		},
+		{
+			name: "backends without segments",
+			transcripts: []*whisper.Transcript{
+				{Text: "第一段", Language: "zh"},
+				{Text: "第二段", Language: "zh"},
+			},
+			want: &whisper.Transcript{
+				Text:     "第一段第二段",
+				Language: "zh",
+				Segments: []whisper.Segment{
+					{Start: 0, End: 12, Text: "第一段"},
+					{Start: 8, End: 20, Text: "第二段"},
+				},
+			},
+		},
+		{
+			name: "silent chunk",
+			transcripts: []*whisper.Transcript{
+				{Language: "en", Segments: []whisper.Segment{{Start: 1, End: 3, Text: "only speech"}}},
+				{Language: ""},
+			},
+			want: &whisper.Transcript{
+				Text:     "only speech",
+				Language: "en",
+				Segments: []whisper.Segment{{Start: 1, End: 3, Text: "only speech"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stitchTranscripts(chunks, tt.transcripts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("stitchTranscripts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStitchTranscriptsLanguage(t *testing.T) {
+	chunks := []video.AudioChunk{
+		{Start: 0, End: 10, CutStart: 0, CutEnd: 10},
+		{Start: 10, End: 20, CutStart: 10, CutEnd: 20},
+		{Start: 20, End: 30, CutStart: 20, CutEnd: 30},
+	}
+	transcripts := []*whisper.Transcript{
+		{Text: "a", Language: "en"},
+		{Text: "b", Language: "zh"},
+		{Text: "c", Language: "zh"},
+	}
+
+	if got := stitchTranscripts(chunks, transcripts).Language; got != "zh" {
+		t.Fatalf("language = %q, want the one most chunks were detected as", got)
+	}
+}
+
+func TestNewChunkPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.WhisperConfig
+		want ChunkPolicy
+	}{
+		{"defaults", config.WhisperConfig{}, ChunkPolicy{Duration: defaultChunkDuration, Overlap: defaultChunkOverlap, Concurrency: defaultChunkConcurrency}},
+		{
+			"configured",
+			config.WhisperConfig{ChunkDuration: "5m", ChunkOverlap: "0s", ChunkConcurrency: 4},
+			ChunkPolicy{Duration: 5 * time.Minute, Overlap: 0, Concurrency: 4},
+		},
+		{
+			"invalid values fall back",
+			config.WhisperConfig{ChunkDuration: "-1m", ChunkOverlap: "soon", ChunkConcurrency: -2},
+			ChunkPolicy{Duration: defaultChunkDuration, Overlap: defaultChunkOverlap, Concurrency: defaultChunkConcurrency},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewChunkPolicy(tt.cfg); got != tt.want {
+				t.Fatalf("NewChunkPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
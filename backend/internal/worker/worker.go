@@ -1,50 +1,73 @@
 package worker
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"opinion-monitor/internal/config"
+	"opinion-monitor/internal/events"
 	"opinion-monitor/internal/models"
+	"opinion-monitor/internal/notifier"
+	"opinion-monitor/internal/webhook"
 	"opinion-monitor/pkg/ai"
 	"opinion-monitor/pkg/video"
 	"opinion-monitor/pkg/whisper"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type WorkerPool struct {
+	instanceID    string
 	cfg           *config.Config
 	db            *gorm.DB
 	queue         *JobQueue
-	aiClient      *ai.OpenAIClient
-	whisperClient *whisper.Client
+	retryPolicy   RetryPolicy
+	aiClient      ai.Client
+	prompts       *ai.PromptStore
+	transcriber   whisper.Transcriber
+	chunkPolicy   ChunkPolicy
 	processor     *video.Processor
+	frameSampling video.FrameSampling
+	events        *events.Bus
+	webhooks      *webhook.Dispatcher
+	notifier      *notifier.Notifier
 }
 
-func NewWorkerPool(cfg *config.Config, db *gorm.DB, queue *JobQueue, whisperClient *whisper.Client) *WorkerPool {
-	aiClient := ai.NewOpenAIClient(
-		cfg.OpenAI.APIBase,
-		cfg.OpenAI.APIKey,
-		cfg.OpenAI.ModelVision,
-		cfg.OpenAI.ModelChat,
-	)
+func NewWorkerPool(cfg *config.Config, db *gorm.DB, queue *JobQueue, aiClient ai.Client, prompts *ai.PromptStore, transcriber whisper.Transcriber, bus *events.Bus, webhooks *webhook.Dispatcher, notifications *notifier.Notifier) *WorkerPool {
+	hostname, _ := os.Hostname()
 
 	return &WorkerPool{
-		cfg:           cfg,
-		db:            db,
-		queue:         queue,
-		aiClient:      aiClient,
-		whisperClient: whisperClient,
-		processor:     video.NewProcessor(),
+		instanceID:  fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		cfg:         cfg,
+		db:          db,
+		queue:       queue,
+		retryPolicy: NewRetryPolicy(cfg.Worker.Retry),
+		aiClient:    aiClient,
+		prompts:     prompts,
+		transcriber: transcriber,
+		chunkPolicy: NewChunkPolicy(cfg.Whisper),
+		processor:   video.NewProcessor(),
+		frameSampling: video.FrameSampling{
+			Mode:           cfg.Frames.Mode,
+			Count:          cfg.Frames.Count,
+			SceneThreshold: cfg.Frames.SceneThreshold,
+			MaxFrames:      cfg.Frames.MaxFrames,
+		},
+		events:   bus,
+		webhooks: webhooks,
+		notifier: notifications,
 	}
 }
 
 func (wp *WorkerPool) Start() {
+	// Pick up work left behind by a previous run before accepting new jobs
+	if err := wp.queue.Recover(); err != nil {
+		log.Printf("Warning: failed to recover stale jobs: %v", err)
+	}
+
 	for i := 0; i < wp.cfg.Worker.Concurrency; i++ {
 		go wp.worker(i)
 	}
@@ -53,24 +76,49 @@ func (wp *WorkerPool) Start() {
 
 func (wp *WorkerPool) worker(id int) {
 	log.Printf("Worker %d started", id)
+	owner := fmt.Sprintf("%s-%d", wp.instanceID, id)
+
+	for {
+		job, err := wp.queue.Claim(owner)
+		if err != nil {
+			log.Printf("Worker %d failed to claim job: %v", id, err)
+			wp.queue.Wait()
+			continue
+		}
+		if job == nil {
+			wp.queue.Wait()
+			continue
+		}
 
-	for videoID := range wp.queue.Jobs() {
+		videoID := job.VideoID
 		log.Printf("Worker %d processing video %d", id, videoID)
 
-		if err := wp.processVideo(videoID); err != nil {
+		startedAt := time.Now()
+		ctx, cancel := context.WithCancel(context.Background())
+		stopKeepAlive := wp.queue.KeepAlive(job.ID, owner, cancel)
+		err = wp.processVideo(ctx, job)
+		stopKeepAlive()
+		cancel()
+
+		// The job belongs to another worker now; leave its state alone
+		if errors.Is(err, ErrLeaseLost) {
+			log.Printf("Worker %d stopped processing video %d: %v", id, videoID, err)
+			continue
+		}
+
+		wp.recordAttempt(job, startedAt, err)
+
+		if err != nil {
 			log.Printf("Worker %d failed to process video %d: %v", id, videoID, err)
-			wp.markJobFailed(videoID, err.Error())
+			wp.handleJobFailure(job, err)
 		}
 	}
 }
 
-func (wp *WorkerPool) processVideo(videoID uint) error {
-	startTime := time.Now()
-
-	// Update job status to processing
-	if err := wp.updateJobStatus(videoID, models.JobStatusProcessing, ""); err != nil {
-		return fmt.Errorf("failed to update job status: %w", err)
-	}
+// processVideo runs the pipeline of a job until it completes, fails or ctx
+// is cancelled because the lease was lost.
+func (wp *WorkerPool) processVideo(ctx context.Context, job *models.Job) error {
+	videoID := job.VideoID
 
 	// Update video status to processing
 	if err := wp.updateVideoStatus(videoID, models.StatusProcessing); err != nil {
@@ -80,130 +128,84 @@ func (wp *WorkerPool) processVideo(videoID uint) error {
 	// Get video info
 	var videoRecord models.Video
 	if err := wp.db.First(&videoRecord, videoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Permanent(fmt.Errorf("failed to get video: %w", err))
+		}
 		return fmt.Errorf("failed to get video: %w", err)
 	}
 
-	// Extract cover frame
-	coverFilename := fmt.Sprintf("cover_%d.jpg", videoID)
-	coverPath := filepath.Join(filepath.Dir(videoRecord.FilePath), coverFilename)
-
-	if err := wp.processor.ExtractCover(videoRecord.FilePath, coverPath, 1.0); err != nil {
-		return fmt.Errorf("failed to extract cover: %w", err)
-	}
-
-	// Update video with cover path
-	if err := wp.db.Model(&videoRecord).Update("cover_path", coverPath).Error; err != nil {
-		return fmt.Errorf("failed to update cover path: %w", err)
-	}
-
-	// Extract audio from video
-	audioFilename := fmt.Sprintf("audio_%d.wav", videoID)
-	audioPath := filepath.Join(filepath.Dir(videoRecord.FilePath), audioFilename)
-
-	if err := wp.processor.ExtractAudio(videoRecord.FilePath, audioPath); err != nil {
-		log.Printf("Warning: failed to extract audio: %v", err)
-		// Continue processing even if audio extraction fails
-	}
-
-	// Update video with audio path
-	if err := wp.db.Model(&videoRecord).Update("audio_path", audioPath).Error; err != nil {
-		return fmt.Errorf("failed to update audio path: %w", err)
-	}
+	// Resolve the prompts for this job, honouring the owner's tenant overrides
+	var owner models.User
+	wp.db.Select("id", "tenant").First(&owner, videoRecord.UserID)
 
-	// Transcribe audio using Whisper
-	var transcriptText string
-	if wp.whisperClient != nil {
-		// Get absolute path for video file
-		absVideoPath := videoRecord.FilePath
-		if !filepath.IsAbs(absVideoPath) {
-			// If relative path, prepend current working directory
-			cwd, err := os.Getwd()
-			if err == nil {
-				absVideoPath = filepath.Join(cwd, absVideoPath)
-			}
-		}
-		
-		transcript, err := wp.whisperClient.TranscribeAudio(absVideoPath)
-		if err != nil {
-			log.Printf("Warning: failed to transcribe audio: %v", err)
-			// Continue processing even if transcription fails
-		} else {
-			transcriptText = transcript
-			log.Printf("Transcribed text: %s", transcriptText)
-
-			// Update video with transcript text
-			if err := wp.db.Model(&videoRecord).Update("transcript_text", transcriptText).Error; err != nil {
-				return fmt.Errorf("failed to update transcript text: %w", err)
-			}
-		}
-	}
-
-	// Extract text from cover using AI
-	coverText, err := wp.aiClient.ExtractTextFromImage(coverPath)
+	prompts, err := wp.prompts.Resolve(job.PromptVersion, owner.Tenant)
 	if err != nil {
-		return fmt.Errorf("failed to extract text from image: %w", err)
-	}
-
-	log.Printf("Extracted cover text: %s", coverText)
-
-	// Combine cover text and transcript for comprehensive analysis
-	var combinedText strings.Builder
-	combinedText.WriteString("封面文字：\n")
-	combinedText.WriteString(coverText)
-	if transcriptText != "" {
-		combinedText.WriteString("\n\n音频转录文字：\n")
-		combinedText.WriteString(transcriptText)
+		if errors.Is(err, ai.ErrUnknownPromptVersion) {
+			return Permanent(err)
+		}
+		return err
 	}
 
-	// Analyze sentiment using combined text
-	report, err := wp.aiClient.AnalyzeSentiment(combinedText.String())
-	if err != nil {
-		return fmt.Errorf("failed to analyze sentiment: %w", err)
+	wp.publish(events.Event{
+		Type:    events.JobStarted,
+		UserID:  videoRecord.UserID,
+		JobID:   job.ID,
+		VideoID: videoID,
+		Attempt: job.RetryCount + 1,
+	})
+
+	state := &pipelineState{
+		ctx:      ctx,
+		JobID:    job.ID,
+		Video:    videoRecord,
+		prompts:  prompts,
+		aiClient: wp.aiClient.WithModels(job.ModelVision, job.ModelChat).WithPrompts(prompts),
 	}
 
-	processingTime := time.Since(startTime).Seconds()
-
-	// Convert arrays to JSON strings
-	keyTopicsJSON, _ := json.Marshal(report.KeyTopics)
-	recommendationsJSON, _ := json.Marshal(report.Recommendations)
-
-	// Save report
-	reportRecord := models.Report{
-		VideoID:          videoID,
-		CoverText:        coverText,
-		TranscriptText:   transcriptText,
-		SentimentScore:   report.SentimentScore,
-		SentimentLabel:   report.SentimentLabel,
-		KeyTopics:        string(keyTopicsJSON),
-		RiskLevel:        report.RiskLevel,
-		DetailedAnalysis: report.DetailedAnalysis,
-		Recommendations:  string(recommendationsJSON),
-		ProcessingTime:   processingTime,
+	if err := wp.runPipeline(state); err != nil {
+		return err
 	}
 
-	if err := wp.db.Create(&reportRecord).Error; err != nil {
-		return fmt.Errorf("failed to save report: %w", err)
+	// Complete the job, then the video, as long as the job is still ours
+	if err := wp.updateJobStatus(job, models.JobStatusCompleted, ""); err != nil {
+		return fmt.Errorf("failed to update job status: %w", err)
 	}
-
-	// Update video status to completed
 	if err := wp.updateVideoStatus(videoID, models.StatusCompleted); err != nil {
 		return fmt.Errorf("failed to update video status: %w", err)
 	}
 
-	// Update job status to completed
-	if err := wp.updateJobStatus(videoID, models.JobStatusCompleted, ""); err != nil {
-		return fmt.Errorf("failed to update job status: %w", err)
-	}
+	wp.publish(events.Event{
+		Type:     events.JobCompleted,
+		UserID:   videoRecord.UserID,
+		JobID:    job.ID,
+		VideoID:  videoID,
+		Progress: 100,
+		ReportID: state.ReportID,
+	})
 
-	log.Printf("Successfully processed video %d in %.2f seconds", videoID, processingTime)
+	log.Printf("Successfully processed video %d in %.2f seconds", videoID, state.ProcessingTime)
 	return nil
 }
 
+// publish sends a job event, looking up the video owner when the caller
+// does not know it.
+func (wp *WorkerPool) publish(e events.Event) {
+	if wp.events == nil {
+		return
+	}
+	if e.UserID == 0 {
+		wp.db.Unscoped().Model(&models.Video{}).Where("id = ?", e.VideoID).Select("user_id").Scan(&e.UserID)
+	}
+	wp.events.Publish(e)
+}
+
 func (wp *WorkerPool) updateVideoStatus(videoID uint, status models.VideoStatus) error {
 	return wp.db.Model(&models.Video{}).Where("id = ?", videoID).Update("status", status).Error
 }
 
-func (wp *WorkerPool) updateJobStatus(videoID uint, status models.JobStatus, errorMsg string) error {
+// updateJobStatus sets the status of a job held by this worker. It returns
+// ErrLeaseLost, changing nothing, when the lease has passed to another worker.
+func (wp *WorkerPool) updateJobStatus(job *models.Job, status models.JobStatus, errorMsg string) error {
 	updates := map[string]interface{}{
 		"status": status,
 	}
@@ -212,10 +214,96 @@ func (wp *WorkerPool) updateJobStatus(videoID uint, status models.JobStatus, err
 		updates["error_message"] = errorMsg
 	}
 
-	return wp.db.Model(&models.Job{}).Where("video_id = ?", videoID).Updates(updates).Error
+	// Release the lease once the job leaves the processing state
+	if status != models.JobStatusProcessing {
+		updates["lease_owner"] = ""
+		updates["lease_expires_at"] = nil
+	}
+
+	return wp.updateLeasedJob(job, updates)
 }
 
-func (wp *WorkerPool) markJobFailed(videoID uint, errorMsg string) {
-	wp.updateVideoStatus(videoID, models.StatusFailed)
-	wp.updateJobStatus(videoID, models.JobStatusFailed, errorMsg)
+// updateLeasedJob applies updates to a job only while this worker holds its
+// lease.
+func (wp *WorkerPool) updateLeasedJob(job *models.Job, updates map[string]interface{}) error {
+	result := wp.db.Model(&models.Job{}).
+		Where("id = ? AND lease_owner = ?", job.ID, job.LeaseOwner).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// handleJobFailure either schedules the job for another attempt after a
+// backoff or moves it to the dead-letter state.
+func (wp *WorkerPool) handleJobFailure(job *models.Job, err error) {
+	attempt := job.RetryCount + 1
+
+	if !wp.retryPolicy.ShouldRetry(attempt, err) {
+		if updateErr := wp.updateLeasedJob(job, map[string]interface{}{
+			"status":           models.JobStatusDead,
+			"retry_count":      attempt,
+			"error_message":    err.Error(),
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		}); updateErr != nil {
+			log.Printf("Warning: failed to dead-letter job %d: %v", job.ID, updateErr)
+			return
+		}
+		log.Printf("Job %d dead-lettered after %d attempt(s)", job.ID, attempt)
+		wp.updateVideoStatus(job.VideoID, models.StatusFailed)
+		wp.publish(events.Event{
+			Type:    events.JobFailed,
+			JobID:   job.ID,
+			VideoID: job.VideoID,
+			Attempt: attempt,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	backoff := wp.retryPolicy.Backoff(attempt)
+	if updateErr := wp.updateLeasedJob(job, map[string]interface{}{
+		"status":           models.JobStatusPending,
+		"retry_count":      attempt,
+		"error_message":    err.Error(),
+		"available_at":     time.Now().Add(backoff),
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+	}); updateErr != nil {
+		log.Printf("Warning: failed to schedule retry of job %d: %v", job.ID, updateErr)
+		return
+	}
+	log.Printf("Job %d will be retried in %s (attempt %d of %d)", job.ID, backoff.Round(time.Second), attempt+1, wp.retryPolicy.MaxAttempts)
+
+	wp.updateVideoStatus(job.VideoID, models.StatusPending)
+	wp.publish(events.Event{
+		Type:    events.JobRetrying,
+		JobID:   job.ID,
+		VideoID: job.VideoID,
+		Attempt: attempt,
+		Error:   err.Error(),
+	})
+}
+
+// recordAttempt stores the outcome of one run of a job.
+func (wp *WorkerPool) recordAttempt(job *models.Job, startedAt time.Time, runErr error) {
+	attempt := models.JobAttempt{
+		JobID:      job.ID,
+		Attempt:    job.RetryCount + 1,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	if runErr != nil {
+		attempt.Error = runErr.Error()
+		attempt.Retryable = wp.retryPolicy.Retryable(runErr)
+	}
+
+	if err := wp.db.Create(&attempt).Error; err != nil {
+		log.Printf("Warning: failed to record attempt for job %d: %v", job.ID, err)
+	}
 }
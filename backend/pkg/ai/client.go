@@ -0,0 +1,84 @@
+package ai
+
+import "fmt"
+
+// Supported providers.
+const (
+	ProviderOpenAI = "openai" // OpenAI or any OpenAI-compatible server such as vLLM
+	ProviderOllama = "ollama" // Ollama's native /api/chat endpoint
+	ProviderFake   = "fake"   // deterministic, offline results for tests and local development
+)
+
+type SentimentReport struct {
+	SentimentScore   float64  `json:"sentiment_score"`
+	SentimentLabel   string   `json:"sentiment_label"`
+	KeyTopics        []string `json:"key_topics"`
+	RiskLevel        string   `json:"risk_level"`
+	DetailedAnalysis string   `json:"detailed_analysis"`
+	Recommendations  []string `json:"recommendations"`
+
+	// Evidence holds the per-chunk findings when the content was too long
+	// for one prompt and was analyzed in parts.
+	Evidence []ChunkEvidence `json:"evidence,omitempty"`
+}
+
+// VisionOCR extracts the text shown in an image.
+type VisionOCR interface {
+	ExtractTextFromImage(imagePath string) (string, error)
+}
+
+// SentimentAnalyzer produces an opinion report for a piece of text.
+type SentimentAnalyzer interface {
+	AnalyzeSentiment(text string) (*SentimentReport, error)
+}
+
+// Summarizer answers a prompt with free-form text, such as the executive
+// summary of a digest.
+type Summarizer interface {
+	Summarize(prompt string) (string, error)
+}
+
+// Client is a model backend providing OCR, sentiment analysis and summaries.
+type Client interface {
+	VisionOCR
+	SentimentAnalyzer
+	Summarizer
+
+	// Models returns the names of the vision and chat models in use.
+	Models() (modelVision, modelChat string)
+	// WithModels returns a copy of the client that uses the given models.
+	// Empty names keep the client's current models.
+	WithModels(modelVision, modelChat string) Client
+	// WithPrompts returns a copy of the client that uses the given prompts.
+	WithPrompts(prompts *Prompts) Client
+}
+
+// Config selects and configures a provider.
+type Config struct {
+	Provider         string
+	BaseURL          string
+	APIKey           string
+	ModelVision      string
+	ModelChat        string
+	StructuredOutput bool
+	MaxInputTokens   int // longer content is analyzed in chunks; 0 disables chunking
+}
+
+// NewClient creates the client for cfg.Provider. An empty provider means OpenAI.
+func NewClient(cfg Config) (Client, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		client := NewOpenAIClient(cfg.BaseURL, cfg.APIKey, cfg.ModelVision, cfg.ModelChat)
+		client.StructuredOutput = cfg.StructuredOutput
+		client.MaxInputTokens = cfg.MaxInputTokens
+		return client, nil
+	case ProviderOllama:
+		client := NewOllamaClient(cfg.BaseURL, cfg.ModelVision, cfg.ModelChat)
+		client.MaxInputTokens = cfg.MaxInputTokens
+		return client, nil
+	case ProviderFake:
+		return NewFakeClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+)
+
+// FakeClient returns deterministic results derived from its input without
+// calling any model. The same input always yields the same report.
+type FakeClient struct {
+	ModelVision string
+	ModelChat   string
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		ModelVision: "fake-vision",
+		ModelChat:   "fake-chat",
+	}
+}
+
+func (c *FakeClient) Models() (modelVision, modelChat string) {
+	return c.ModelVision, c.ModelChat
+}
+
+func (c *FakeClient) WithModels(modelVision, modelChat string) Client {
+	clone := *c
+	if modelVision != "" {
+		clone.ModelVision = modelVision
+	}
+	if modelChat != "" {
+		clone.ModelChat = modelChat
+	}
+	return &clone
+}
+
+// WithPrompts returns the client unchanged; fake results do not depend on prompts.
+func (c *FakeClient) WithPrompts(prompts *Prompts) Client {
+	return c
+}
+
+func (c *FakeClient) ExtractTextFromImage(imagePath string) (string, error) {
+	return fmt.Sprintf("text of %s", filepath.Base(imagePath)), nil
+}
+
+func (c *FakeClient) AnalyzeSentiment(text string) (*SentimentReport, error) {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	score := float64(h.Sum32()%101) / 100
+
+	label := "neutral"
+	switch {
+	case score >= 0.6:
+		label = "positive"
+	case score < 0.4:
+		label = "negative"
+	}
+
+	risk := "low"
+	switch {
+	case score < 0.2:
+		risk = "high"
+	case score < 0.4:
+		risk = "medium"
+	}
+
+	topics := strings.Fields(text)
+	if len(topics) > minKeyTopics {
+		topics = topics[:minKeyTopics]
+	}
+	for len(topics) < minKeyTopics {
+		topics = append(topics, fmt.Sprintf("topic %d", len(topics)+1))
+	}
+
+	return &SentimentReport{
+		SentimentScore:   score,
+		SentimentLabel:   label,
+		KeyTopics:        topics,
+		RiskLevel:        risk,
+		DetailedAnalysis: fmt.Sprintf("Fake analysis of %d characters of content.", len([]rune(text))),
+		Recommendations:  []string{"No action required (fake provider)"},
+	}, nil
+}
+
+func (c *FakeClient) Summarize(prompt string) (string, error) {
+	return fmt.Sprintf("Fake summary of a %d-character prompt.", len([]rune(prompt))), nil
+}
@@ -0,0 +1,335 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// minChunkTokens keeps chunks useful when a prompt template leaves
+	// little room for content.
+	minChunkTokens = 500
+
+	// mapConcurrency is how many chunks are analyzed at the same time.
+	mapConcurrency = 3
+
+	excerptLength        = 80  // runes of chunk text quoted as evidence
+	shortSummaryLength   = 200 // runes kept per chunk when the reduce prompt is too long
+	sentenceTerminators  = "。！？!?；;"
+	chunkHeaderTemplate  = "（以下为长内容的第%d/%d部分，请仅分析本部分）\n"
+	chunkHeaderMaxTokens = 32
+)
+
+// ChunkEvidence is what the analysis of one part of a long text found. It is
+// kept on the merged report so findings can be traced to the text.
+type ChunkEvidence struct {
+	Chunk          int      `json:"chunk"` // 1-based
+	Excerpt        string   `json:"excerpt"`
+	SentimentScore float64  `json:"sentiment_score"`
+	SentimentLabel string   `json:"sentiment_label"`
+	RiskLevel      string   `json:"risk_level"`
+	KeyTopics      []string `json:"key_topics"`
+	Summary        string   `json:"summary"`
+}
+
+// EstimateTokens approximates the number of tokens text uses: one per CJK
+// character and one per four bytes of anything else. It errs on the high
+// side for most tokenizers.
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += len(string(r))
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// SplitText cuts text into chunks of at most maxTokens estimated tokens,
+// preferring to break at line ends, then at sentence ends.
+func SplitText(text string, maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		// Whitespace between chunks is dropped, not sent as a chunk of its own
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+
+	for _, piece := range splitPieces(text, maxTokens) {
+		tokens := EstimateTokens(piece)
+		if currentTokens+tokens > maxTokens && current.Len() > 0 {
+			flush()
+		}
+		current.WriteString(piece)
+		currentTokens += tokens
+	}
+	flush()
+
+	return chunks
+}
+
+// splitPieces breaks text into lines, and lines that are too long into
+// sentences or, failing that, fixed-size pieces.
+func splitPieces(text string, maxTokens int) []string {
+	var pieces []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if EstimateTokens(line) <= maxTokens {
+			pieces = append(pieces, line)
+			continue
+		}
+		for _, sentence := range splitSentences(line) {
+			if EstimateTokens(sentence) <= maxTokens {
+				pieces = append(pieces, sentence)
+			} else {
+				pieces = append(pieces, splitRunes(sentence, maxTokens)...)
+			}
+		}
+	}
+	return pieces
+}
+
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		if strings.ContainsRune(sentenceTerminators, r) {
+			end := i + len(string(r))
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// splitRunes cuts text into pieces of maxTokens estimated tokens, counting
+// the tokens of the current piece as it grows the way EstimateTokens does.
+func splitRunes(text string, maxTokens int) []string {
+	var pieces []string
+	start, cjk, other := 0, 0, 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += len(string(r))
+		}
+		if cjk+(other+3)/4 >= maxTokens {
+			pieces = append(pieces, text[start:i])
+			start, cjk, other = i, 0, 0
+		}
+	}
+	if start < len(text) {
+		pieces = append(pieces, text[start:])
+	}
+	return pieces
+}
+
+// analyzeText analyzes text in a single prompt when it fits in
+// maxInputTokens. Longer text is split into chunks that are analyzed
+// separately (map), then the chunk findings are merged into one report by
+// the model (reduce). A maxInputTokens of zero disables chunking.
+func analyzeText(text string, prompts *Prompts, maxInputTokens int, complete func(messages []chatMessage) (string, error)) (*SentimentReport, error) {
+	prompt, err := prompts.Sentiment(text)
+	if err != nil {
+		return nil, err
+	}
+	if maxInputTokens <= 0 || EstimateTokens(prompt) <= maxInputTokens {
+		return analyzeWithRepair(prompt, complete)
+	}
+
+	emptyPrompt, err := prompts.Sentiment("")
+	if err != nil {
+		return nil, err
+	}
+	budget := maxInputTokens - EstimateTokens(emptyPrompt) - chunkHeaderMaxTokens
+	if budget < minChunkTokens {
+		budget = minChunkTokens
+	}
+
+	chunks := SplitText(text, budget)
+	reports, err := analyzeChunks(chunks, prompts, complete)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence := make([]ChunkEvidence, len(chunks))
+	for i, report := range reports {
+		evidence[i] = ChunkEvidence{
+			Chunk:          i + 1,
+			Excerpt:        truncateRunes(chunks[i], excerptLength),
+			SentimentScore: report.SentimentScore,
+			SentimentLabel: report.SentimentLabel,
+			RiskLevel:      report.RiskLevel,
+			KeyTopics:      report.KeyTopics,
+			Summary:        report.DetailedAnalysis,
+		}
+	}
+
+	merged, err := reduceChunks(evidence, prompts, maxInputTokens, complete)
+	if err != nil {
+		return nil, err
+	}
+	merged.Evidence = evidence
+	return merged, nil
+}
+
+// analyzeChunks runs the sentiment prompt on every chunk.
+func analyzeChunks(chunks []string, prompts *Prompts, complete func(messages []chatMessage) (string, error)) ([]*SentimentReport, error) {
+	reports := make([]*SentimentReport, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, mapConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			content := fmt.Sprintf(chunkHeaderTemplate, i+1, len(chunks)) + chunks[i]
+			prompt, err := prompts.Sentiment(content)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			reports[i], err = analyzeWithRepair(prompt, complete)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to analyze chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// reduceChunks asks the model to merge the chunk findings into one report.
+// Chunk summaries are shortened when they do not fit in the prompt. When
+// even the shortened ones do not, runs of consecutive chunks that fit are
+// merged first and their results are merged in turn.
+func reduceChunks(evidence []ChunkEvidence, prompts *Prompts, maxInputTokens int, complete func(messages []chatMessage) (string, error)) (*SentimentReport, error) {
+	prompt, err := reducePrompt(evidence, prompts, maxInputTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if prompt == "" {
+		if len(evidence) < 2 {
+			return nil, fmt.Errorf("reduce prompt does not fit in %d tokens", maxInputTokens)
+		}
+		groups, err := groupEvidence(evidence, prompts, maxInputTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := make([]ChunkEvidence, len(groups))
+		for i, group := range groups {
+			if len(group) == 1 {
+				merged[i] = group[0]
+				continue
+			}
+			report, err := reduceChunks(group, prompts, maxInputTokens, complete)
+			if err != nil {
+				return nil, err
+			}
+			// Numbered after the group's first chunk, so the final analysis
+			// still points at chunks of the evidence
+			merged[i] = ChunkEvidence{
+				Chunk:          group[0].Chunk,
+				Excerpt:        group[0].Excerpt,
+				SentimentScore: report.SentimentScore,
+				SentimentLabel: report.SentimentLabel,
+				RiskLevel:      report.RiskLevel,
+				KeyTopics:      report.KeyTopics,
+				Summary:        report.DetailedAnalysis,
+			}
+		}
+		return reduceChunks(merged, prompts, maxInputTokens, complete)
+	}
+
+	report, err := analyzeWithRepair(prompt, complete)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge chunk analyses: %w", err)
+	}
+	return report, nil
+}
+
+// reducePrompt renders the reduce prompt for evidence, shortening the chunk
+// summaries if it is longer than maxInputTokens. It returns "" when even the
+// shortened prompt is too long.
+func reducePrompt(evidence []ChunkEvidence, prompts *Prompts, maxInputTokens int) (string, error) {
+	prompt, err := prompts.Reduce(evidence)
+	if err != nil || EstimateTokens(prompt) <= maxInputTokens {
+		return prompt, err
+	}
+
+	short := make([]ChunkEvidence, len(evidence))
+	for i, e := range evidence {
+		e.Summary = truncateRunes(e.Summary, shortSummaryLength)
+		short[i] = e
+	}
+	prompt, err = prompts.Reduce(short)
+	if err != nil || EstimateTokens(prompt) <= maxInputTokens {
+		return prompt, err
+	}
+	return "", nil
+}
+
+// groupEvidence cuts evidence into runs of consecutive chunks whose reduce
+// prompt fits in maxInputTokens. Only the last run may have a single chunk.
+func groupEvidence(evidence []ChunkEvidence, prompts *Prompts, maxInputTokens int) ([][]ChunkEvidence, error) {
+	var groups [][]ChunkEvidence
+	for start := 0; start < len(evidence); {
+		end := start + 1
+		for end < len(evidence) {
+			prompt, err := reducePrompt(evidence[start:end+1], prompts, maxInputTokens)
+			if err != nil {
+				return nil, err
+			}
+			if prompt == "" {
+				break
+			}
+			end++
+		}
+		if end-start < 2 && end < len(evidence) {
+			return nil, fmt.Errorf("reduce prompt does not fit two chunk analyses in %d tokens", maxInputTokens)
+		}
+		groups = append(groups, evidence[start:end])
+		start = end
+	}
+	return groups, nil
+}
+
+func truncateRunes(text string, n int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "…"
+}
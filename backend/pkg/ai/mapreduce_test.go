@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"one byte rounds up", "a", 1},
+		{"four bytes", "abcd", 1},
+		{"five bytes", "abcde", 2},
+		{"cjk per character", "舆情监测", 4},
+		{"kana and hangul", "ひらカタ한글", 6},
+		{"mixed", "视频 video", 2 + 2},
+		{"other multibyte by bytes", "café", 2}, // é is two bytes
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Fatalf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{"fits", "short text", 10, []string{"short text"}},
+		{"empty", "", 10, nil},
+		{"whitespace only", " \n \n", 10, nil},
+		{
+			"lines are packed up to the limit",
+			"aaaa\nbbbb\ncccc\ndddd\n",
+			4, // every line is two tokens with its newline
+			[]string{"aaaa\nbbbb", "cccc\ndddd"},
+		},
+		{
+			"exactly at the limit stays one chunk",
+			"一二三\n四五",
+			6,
+			[]string{"一二三\n四五"},
+		},
+		{
+			"one over the limit is split",
+			"一二三\n四五六",
+			6,
+			[]string{"一二三", "四五六"},
+		},
+		{
+			"long lines break at sentence ends",
+			"第一句话。第二句话！第三句话？",
+			6,
+			[]string{"第一句话。", "第二句话！", "第三句话？"},
+		},
+		{
+			"long sentences break into fixed pieces",
+			"一二三四五六七八九十",
+			4,
+			[]string{"一二三四", "五六七八", "九十"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.maxTokens)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextLimits(t *testing.T) {
+	text := strings.Repeat("今天的新闻引起了广泛关注，评论区出现了大量负面情绪。", 40) + "\n" +
+		strings.Repeat("The video shows a crowded street and a long queue outside the store. ", 30) + "\n" +
+		strings.Repeat("x", 3000)
+
+	for _, maxTokens := range []int{1, 7, 50, 333, 1000} {
+		chunks := SplitText(text, maxTokens)
+		for i, chunk := range chunks {
+			if tokens := EstimateTokens(chunk); tokens > maxTokens {
+				t.Errorf("maxTokens %d: chunk %d has %d tokens", maxTokens, i, tokens)
+			}
+			if chunk == "" {
+				t.Errorf("maxTokens %d: chunk %d is empty", maxTokens, i)
+			}
+		}
+
+		// Nothing but surrounding whitespace is lost
+		joined := strings.Join(strings.Fields(strings.Join(chunks, "")), "")
+		if want := strings.Join(strings.Fields(text), ""); joined != want {
+			t.Errorf("maxTokens %d: chunks do not add up to the text", maxTokens)
+		}
+	}
+}
+
+// naiveSplitRunes is splitRunes as first written, re-estimating the whole
+// piece after every rune.
+func naiveSplitRunes(text string, maxTokens int) []string {
+	var pieces []string
+	var current strings.Builder
+	for _, r := range text {
+		current.WriteRune(r)
+		if EstimateTokens(current.String()) >= maxTokens {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return pieces
+}
+
+func TestSplitRunes(t *testing.T) {
+	texts := []string{
+		"",
+		"abcdefghij",
+		"一二三四五六七八九十",
+		"视频video内容content舆情analysis",
+		"café naïve ひらがな 한국어 " + strings.Repeat("é", 9),
+	}
+
+	for _, text := range texts {
+		for _, maxTokens := range []int{1, 2, 3, 7} {
+			got := splitRunes(text, maxTokens)
+			if want := naiveSplitRunes(text, maxTokens); !reflect.DeepEqual(got, want) {
+				t.Errorf("splitRunes(%q, %d) = %q, want %q", text, maxTokens, got, want)
+			}
+		}
+	}
+}
+
+func TestSplitRunesLongText(t *testing.T) {
+	// Linear in the text: a megabyte without a break splits quickly
+	text := strings.Repeat("舆情x", 1<<18)
+	pieces := splitRunes(text, 1000)
+	if got := strings.Join(pieces, ""); got != text {
+		t.Fatalf("pieces do not add up to the text")
+	}
+	for i, piece := range pieces {
+		if tokens := EstimateTokens(piece); tokens > 1000 {
+			t.Fatalf("piece %d has %d tokens", i, tokens)
+		}
+	}
+}
+
+const mergedReport = `{"sentiment_score": 0.3, "sentiment_label": "negative", "key_topics": ["a", "b", "c"], ` +
+	`"risk_level": "medium", "detailed_analysis": "merged", "recommendations": ["watch"]}`
+
+func TestReduceChunks(t *testing.T) {
+	prompts := &Prompts{
+		Version: "test",
+		reduce:  template.Must(template.New("reduce").Parse(`{{range .Chunks}}[{{.Chunk}} {{.Summary}}]{{end}}`)),
+	}
+	evidence := func(n int, summary string) []ChunkEvidence {
+		evidence := make([]ChunkEvidence, n)
+		for i := range evidence {
+			evidence[i] = ChunkEvidence{Chunk: i + 1, Summary: summary}
+		}
+		return evidence
+	}
+
+	tests := []struct {
+		name           string
+		evidence       []ChunkEvidence
+		maxInputTokens int
+		wantPrompts    []string
+		wantErr        string
+	}{
+		{
+			name:           "fits",
+			evidence:       evidence(3, "abcd"),
+			maxInputTokens: 100,
+			wantPrompts:    []string{"[1 abcd][2 abcd][3 abcd]"},
+		},
+		{
+			name:           "summaries are shortened",
+			evidence:       evidence(2, strings.Repeat("长", 300)),
+			maxInputTokens: 420,
+			wantPrompts:    []string{"[1 " + strings.Repeat("长", 200) + "…][2 " + strings.Repeat("长", 200) + "…]"},
+		},
+		{
+			name:           "merged in groups when shortening is not enough",
+			evidence:       evidence(5, strings.Repeat("长", 200)),
+			maxInputTokens: 420,
+			wantPrompts: []string{
+				"[1 " + strings.Repeat("长", 200) + "][2 " + strings.Repeat("长", 200) + "]",
+				"[3 " + strings.Repeat("长", 200) + "][4 " + strings.Repeat("长", 200) + "]",
+				"[1 merged][3 merged][5 " + strings.Repeat("长", 200) + "]",
+			},
+		},
+		{
+			name:           "two chunks never fit",
+			evidence:       evidence(3, strings.Repeat("长", 200)),
+			maxInputTokens: 300,
+			wantErr:        "does not fit two chunk analyses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			complete := func(messages []chatMessage) (string, error) {
+				sent = append(sent, messages[0].Content)
+				return mergedReport, nil
+			}
+
+			report, err := reduceChunks(tt.evidence, prompts, tt.maxInputTokens, complete)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("reduceChunks() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reduceChunks() error = %v", err)
+			}
+			if report.DetailedAnalysis != "merged" {
+				t.Fatalf("reduceChunks() = %+v", report)
+			}
+			if !reflect.DeepEqual(sent, tt.wantPrompts) {
+				t.Fatalf("prompts sent = %q, want %q", sent, tt.wantPrompts)
+			}
+			for i, prompt := range sent {
+				if tokens := EstimateTokens(prompt); tokens > tt.maxInputTokens {
+					t.Errorf("prompt %d has %d tokens, over %d", i, tokens, tt.maxInputTokens)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OllamaClient talks to a local Ollama server through its native chat API,
+// so images and JSON mode work without an OpenAI-compatible proxy.
+type OllamaClient struct {
+	baseURL     string
+	httpClient  *http.Client
+	ModelVision string
+	ModelChat   string
+	prompts     *Prompts
+
+	MaxInputTokens int // longer content is analyzed in chunks; 0 disables chunking
+}
+
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // base64 encoded
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   interface{}     `json:"format,omitempty"` // "json" or a JSON schema
+}
+
+type ollamaChatResponse struct {
+	Message ollamaMessage `json:"message"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func NewOllamaClient(baseURL, modelVision, modelChat string) *OllamaClient {
+	return &OllamaClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Minute, // Local models can be slow on long prompts
+		},
+		ModelVision: modelVision,
+		ModelChat:   modelChat,
+		prompts:     builtin(),
+	}
+}
+
+func (c *OllamaClient) Models() (modelVision, modelChat string) {
+	return c.ModelVision, c.ModelChat
+}
+
+func (c *OllamaClient) WithModels(modelVision, modelChat string) Client {
+	clone := *c
+	if modelVision != "" {
+		clone.ModelVision = modelVision
+	}
+	if modelChat != "" {
+		clone.ModelChat = modelChat
+	}
+	return &clone
+}
+
+func (c *OllamaClient) WithPrompts(prompts *Prompts) Client {
+	clone := *c
+	clone.prompts = prompts
+	return &clone
+}
+
+func (c *OllamaClient) ExtractTextFromImage(imagePath string) (string, error) {
+	imageData, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	return c.chat(ollamaChatRequest{
+		Model: c.ModelVision,
+		Messages: []ollamaMessage{{
+			Role:    "user",
+			Content: c.prompts.OCR,
+			Images:  []string{base64.StdEncoding.EncodeToString(imageData)},
+		}},
+	})
+}
+
+func (c *OllamaClient) AnalyzeSentiment(text string) (*SentimentReport, error) {
+	return analyzeText(text, c.prompts, c.MaxInputTokens, c.completeReport)
+}
+
+// Summarize sends the prompt to the chat model and returns its plain-text answer.
+func (c *OllamaClient) Summarize(prompt string) (string, error) {
+	content, err := c.chat(ollamaChatRequest{
+		Model:    c.ModelChat,
+		Messages: []ollamaMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// completeReport sends a chat that is expected to answer with a SentimentReport.
+func (c *OllamaClient) completeReport(messages []chatMessage) (string, error) {
+	reqMessages := make([]ollamaMessage, 0, len(messages))
+	for _, m := range messages {
+		reqMessages = append(reqMessages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+
+	return c.chat(ollamaChatRequest{
+		Model:    c.ModelChat,
+		Messages: reqMessages,
+		Format:   sentimentSchema,
+	})
+}
+
+func (c *OllamaClient) chat(reqBody ollamaChatRequest) (string, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", c.baseURL)
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to call Ollama API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+	}
+
+	if resp.StatusCode != http.StatusOK || chatResp.Error != "" {
+		return "", fmt.Errorf("Ollama API returned status %d: %s", resp.StatusCode, chatResp.Error)
+	}
+
+	return chatResp.Message.Content, nil
+}
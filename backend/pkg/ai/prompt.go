@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultPromptVersion is the built-in prompt version shipped with the binary.
+const DefaultPromptVersion = "v1"
+
+const (
+	ocrTemplate       = "ocr"
+	sentimentTemplate = "sentiment"
+	reduceTemplate    = "reduce" // optional; falls back to the built-in default version
+	digestTemplate    = "digest" // optional; falls back to the built-in default version
+)
+
+//go:embed prompts
+var builtinPrompts embed.FS
+
+// ErrUnknownPromptVersion is returned when no templates exist for a version.
+var ErrUnknownPromptVersion = errors.New("unknown prompt version")
+
+var validPromptName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Prompts is a resolved set of prompt templates.
+type Prompts struct {
+	// Version identifies the templates, with "/<tenant>" appended when a
+	// tenant override was used, e.g. "v2/acme".
+	Version   string
+	OCR       string
+	sentiment *template.Template
+	reduce    *template.Template
+	digest    *template.Template
+}
+
+// Sentiment renders the analysis prompt for the given content.
+func (p *Prompts) Sentiment(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := p.sentiment.Execute(&buf, struct{ Content string }{Content: content}); err != nil {
+		return "", fmt.Errorf("failed to render sentiment prompt %s: %w", p.Version, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Reduce renders the prompt that merges the analyses of the chunks of a long
+// text into one report.
+func (p *Prompts) Reduce(chunks []ChunkEvidence) (string, error) {
+	var buf bytes.Buffer
+	data := struct {
+		Total  int
+		Chunks []ChunkEvidence
+	}{Total: len(chunks), Chunks: chunks}
+	if err := p.reduce.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render reduce prompt %s: %w", p.Version, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Digest renders the prompt asking for the executive summary of a digest.
+// data is the digest's statistics, see digest.SummaryInput.
+func (p *Prompts) Digest(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := p.digest.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render digest prompt %s: %w", p.Version, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// PromptStore loads prompt templates by version. Templates are read from
+//
+//	<dir>/<version>/<name>.tmpl
+//	<dir>/<version>/tenants/<tenant>/<name>.tmpl   (per-tenant override)
+//
+// on every Resolve, so edits take effect without a restart. Versions shipped
+// with the binary are used when a file does not exist on disk.
+type PromptStore struct {
+	dir            string
+	defaultVersion string
+}
+
+func NewPromptStore(dir, defaultVersion string) *PromptStore {
+	if defaultVersion == "" {
+		defaultVersion = DefaultPromptVersion
+	}
+	return &PromptStore{dir: dir, defaultVersion: defaultVersion}
+}
+
+// DefaultVersion returns the version used when none is requested.
+func (s *PromptStore) DefaultVersion() string {
+	return s.defaultVersion
+}
+
+// Resolve loads the templates of a version for a tenant. An empty version
+// means the default one; an empty tenant means no overrides.
+func (s *PromptStore) Resolve(version, tenant string) (*Prompts, error) {
+	if version == "" {
+		version = s.defaultVersion
+	}
+	if !validPromptName.MatchString(version) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPromptVersion, version)
+	}
+	if tenant != "" && !ValidTenantName(tenant) {
+		log.Printf("Warning: ignoring prompt overrides of invalid tenant name %q", tenant)
+		tenant = ""
+	}
+
+	ocr, ocrOverridden, err := s.load(version, tenant, ocrTemplate)
+	if err != nil {
+		return nil, err
+	}
+	sentimentText, sentimentOverridden, err := s.load(version, tenant, sentimentTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	reduceText, reduceOverridden, err := s.load(version, tenant, reduceTemplate)
+	if errors.Is(err, ErrUnknownPromptVersion) {
+		reduceText, _, err = s.load(DefaultPromptVersion, "", reduceTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	digestText, digestOverridden, err := s.load(version, tenant, digestTemplate)
+	if errors.Is(err, ErrUnknownPromptVersion) {
+		digestText, _, err = s.load(DefaultPromptVersion, "", digestTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sentiment, err := template.New(sentimentTemplate).Option("missingkey=error").Parse(sentimentText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sentiment prompt %s: %w", version, err)
+	}
+	reduce, err := template.New(reduceTemplate).Option("missingkey=error").Parse(reduceText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reduce prompt %s: %w", version, err)
+	}
+	digest, err := template.New(digestTemplate).Option("missingkey=error").Parse(digestText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse digest prompt %s: %w", version, err)
+	}
+
+	resolved := version
+	if ocrOverridden || sentimentOverridden || reduceOverridden || digestOverridden {
+		resolved = version + "/" + tenant
+	}
+
+	return &Prompts{
+		Version:   resolved,
+		OCR:       strings.TrimSpace(ocr),
+		sentiment: sentiment,
+		reduce:    reduce,
+		digest:    digest,
+	}, nil
+}
+
+// ValidTenantName reports whether name can select tenant prompt overrides:
+// letters, digits, dots, dashes and underscores, not starting with a symbol.
+func ValidTenantName(name string) bool {
+	return validPromptName.MatchString(name)
+}
+
+// HasVersion reports whether templates exist for version.
+func (s *PromptStore) HasVersion(version string) bool {
+	_, err := s.Resolve(version, "")
+	return err == nil
+}
+
+// load returns the named template, preferring the tenant override, then the
+// version on disk, then the built-in copy.
+func (s *PromptStore) load(version, tenant, name string) (string, bool, error) {
+	filename := name + ".tmpl"
+
+	if s.dir != "" {
+		if tenant != "" {
+			data, err := os.ReadFile(filepath.Join(s.dir, version, "tenants", tenant, filename))
+			if err == nil {
+				return string(data), true, nil
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, version, filename))
+		if err == nil {
+			return string(data), false, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", false, fmt.Errorf("failed to read prompt %s/%s: %w", version, filename, err)
+		}
+	}
+
+	data, err := builtinPrompts.ReadFile("prompts/" + version + "/" + filename)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %s (missing %s)", ErrUnknownPromptVersion, version, filename)
+	}
+	return string(data), false, nil
+}
+
+// builtin returns the default built-in prompts, used by clients that were
+// not given any.
+func builtin() *Prompts {
+	prompts, err := NewPromptStore("", DefaultPromptVersion).Resolve("", "")
+	if err != nil {
+		panic(err)
+	}
+	return prompts
+}
+
+// parseSentimentReport decodes a model response into a SentimentReport.
+func parseSentimentReport(content string) (*SentimentReport, error) {
+	// 清理 markdown 代码块（如果存在）
+	content = cleanJSONResponse(content)
+
+	var report SentimentReport
+	if err := json.Unmarshal([]byte(content), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse sentiment report: %w, content: %s", err, content)
+	}
+
+	return &report, nil
+}
+
+func cleanJSONResponse(content string) string {
+	// 移除 markdown 代码块标记
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	minKeyTopics = 3
+	maxKeyTopics = 6
+
+	// maxRepairAttempts is how many times an invalid answer is sent back to
+	// the model for correction before giving up.
+	maxRepairAttempts = 1
+)
+
+var (
+	sentimentLabels = []string{"positive", "neutral", "negative"}
+	riskLevels      = []string{"high", "medium", "low"}
+)
+
+// sentimentSchema is the JSON schema of SentimentReport, used for structured
+// output where the backend supports it.
+var sentimentSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"sentiment_score": map[string]interface{}{
+			"type":    "number",
+			"minimum": 0,
+			"maximum": 1,
+		},
+		"sentiment_label": map[string]interface{}{
+			"type": "string",
+			"enum": sentimentLabels,
+		},
+		"key_topics": map[string]interface{}{
+			"type":     "array",
+			"items":    map[string]interface{}{"type": "string"},
+			"minItems": minKeyTopics,
+			"maxItems": maxKeyTopics,
+		},
+		"risk_level": map[string]interface{}{
+			"type": "string",
+			"enum": riskLevels,
+		},
+		"detailed_analysis": map[string]interface{}{
+			"type": "string",
+		},
+		"recommendations": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+	},
+	"required": []string{
+		"sentiment_score", "sentiment_label", "key_topics",
+		"risk_level", "detailed_analysis", "recommendations",
+	},
+	"additionalProperties": false,
+}
+
+// ValidationError lists everything wrong with a SentimentReport.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid sentiment report: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the report against the rules given to the model in the prompt.
+func (r *SentimentReport) Validate() error {
+	var problems []string
+
+	if r.SentimentScore < 0 || r.SentimentScore > 1 {
+		problems = append(problems, fmt.Sprintf("sentiment_score %.2f is outside 0.0-1.0", r.SentimentScore))
+	}
+
+	if !contains(sentimentLabels, r.SentimentLabel) {
+		problems = append(problems, fmt.Sprintf("sentiment_label %q must be one of %s", r.SentimentLabel, strings.Join(sentimentLabels, ", ")))
+	} else if expected := labelForScore(r.SentimentScore); r.SentimentLabel != expected {
+		problems = append(problems, fmt.Sprintf("sentiment_label %q does not match sentiment_score %.2f (expected %q)", r.SentimentLabel, r.SentimentScore, expected))
+	}
+
+	if !contains(riskLevels, r.RiskLevel) {
+		problems = append(problems, fmt.Sprintf("risk_level %q must be one of %s", r.RiskLevel, strings.Join(riskLevels, ", ")))
+	}
+
+	if n := len(r.KeyTopics); n < minKeyTopics || n > maxKeyTopics {
+		problems = append(problems, fmt.Sprintf("key_topics has %d items, expected %d-%d", n, minKeyTopics, maxKeyTopics))
+	}
+
+	if strings.TrimSpace(r.DetailedAnalysis) == "" {
+		problems = append(problems, "detailed_analysis is empty")
+	}
+
+	if len(r.Recommendations) == 0 {
+		problems = append(problems, "recommendations is empty")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// labelForScore returns the sentiment label the prompt prescribes for a score.
+func labelForScore(score float64) string {
+	switch {
+	case score >= 0.6:
+		return "positive"
+	case score >= 0.4:
+		return "neutral"
+	default:
+		return "negative"
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// chatMessage is a provider-neutral chat turn.
+type chatMessage struct {
+	Role    string
+	Content string
+}
+
+// analyzeWithRepair sends prompt through complete and validates the answer.
+// When the answer cannot be parsed or fails validation, the problems are sent
+// back to the model for correction.
+func analyzeWithRepair(prompt string, complete func(messages []chatMessage) (string, error)) (*SentimentReport, error) {
+	messages := []chatMessage{{Role: "user", Content: prompt}}
+
+	for attempt := 0; ; attempt++ {
+		content, err := complete(messages)
+		if err != nil {
+			return nil, err
+		}
+
+		report, err := parseSentimentReport(content)
+		if err == nil {
+			err = report.Validate()
+		}
+		if err == nil {
+			return report, nil
+		}
+		if attempt >= maxRepairAttempts {
+			return nil, err
+		}
+
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: content},
+			chatMessage{Role: "user", Content: repairPrompt(err)},
+		)
+	}
+}
+
+// repairPrompt asks the model to fix the problems found in its previous answer.
+func repairPrompt(err error) string {
+	return fmt.Sprintf(`你上一次返回的结果不符合要求：%s
+
+请修正上述问题，并严格按照要求的JSON格式重新返回完整结果（不要包含markdown代码块标记或其他任何文字）。`, err.Error())
+}
@@ -0,0 +1,144 @@
+package video
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// Silence detection settings passed to ffmpeg's silencedetect filter.
+const (
+	silenceNoise       = "-35dB"
+	silenceMinDuration = 0.4 // seconds
+)
+
+var (
+	silenceStartPattern = regexp.MustCompile(`silence_start:\s*(-?[0-9.]+)`)
+	silenceEndPattern   = regexp.MustCompile(`silence_end:\s*([0-9.]+)`)
+)
+
+// Silence is a quiet stretch of audio, in seconds.
+type Silence struct {
+	Start float64
+	End   float64
+}
+
+// AudioChunk is a piece of a longer audio file. Start and End are the span
+// that is extracted, including the overlap with its neighbours; the chunk is
+// responsible for the speech between CutStart and CutEnd.
+type AudioChunk struct {
+	Index    int
+	Path     string
+	Start    float64
+	End      float64
+	CutStart float64
+	CutEnd   float64
+}
+
+// DetectSilences lists the silent stretches of an audio file.
+func (p *Processor) DetectSilences(audioPath string) ([]Silence, error) {
+	cmd := exec.Command("ffmpeg",
+		"-i", audioPath,
+		"-af", fmt.Sprintf("silencedetect=noise=%s:d=%.2f", silenceNoise, silenceMinDuration),
+		"-f", "null",
+		"-",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w, output: %s", err, string(output))
+	}
+
+	starts := silenceStartPattern.FindAllStringSubmatch(string(output), -1)
+	ends := silenceEndPattern.FindAllStringSubmatch(string(output), -1)
+
+	silences := make([]Silence, 0, len(ends))
+	for i := 0; i < len(starts) && i < len(ends); i++ {
+		start, _ := strconv.ParseFloat(starts[i][1], 64)
+		end, _ := strconv.ParseFloat(ends[i][1], 64)
+		if start < 0 {
+			start = 0
+		}
+		silences = append(silences, Silence{Start: start, End: end})
+	}
+
+	return silences, nil
+}
+
+// PlanAudioChunks splits duration seconds of audio into chunks of about
+// chunkDuration seconds. Each cut is moved back to the middle of a silence
+// when one is found within the last quarter of the chunk, so words are not
+// split, and every chunk is extended by overlap seconds on both sides.
+func PlanAudioChunks(duration, chunkDuration, overlap float64, silences []Silence) []AudioChunk {
+	if chunkDuration <= 0 || duration <= chunkDuration {
+		return []AudioChunk{{Start: 0, End: duration, CutStart: 0, CutEnd: duration}}
+	}
+
+	var chunks []AudioChunk
+	cutStart := 0.0
+	for cutStart < duration {
+		cutEnd := cutStart + chunkDuration
+		if cutEnd >= duration {
+			cutEnd = duration
+		} else if cut, ok := silenceBefore(silences, cutEnd-chunkDuration/4, cutEnd); ok {
+			cutEnd = cut
+		}
+
+		chunks = append(chunks, AudioChunk{
+			Index:    len(chunks),
+			Start:    max(0, cutStart-overlap),
+			End:      min(duration, cutEnd+overlap),
+			CutStart: cutStart,
+			CutEnd:   cutEnd,
+		})
+		cutStart = cutEnd
+	}
+
+	return chunks
+}
+
+// silenceBefore returns the middle of the latest silence centered between
+// from and to.
+func silenceBefore(silences []Silence, from, to float64) (float64, bool) {
+	found := false
+	best := 0.0
+	for _, s := range silences {
+		mid := (s.Start + s.End) / 2
+		if mid > from && mid < to && mid > best {
+			best = mid
+			found = true
+		}
+	}
+	return best, found
+}
+
+// ExtractAudioChunk writes the span of the chunk as a 16kHz mono WAV file
+// into outputDir and sets chunk.Path.
+func (p *Processor) ExtractAudioChunk(audioPath, outputDir string, chunk *AudioChunk) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, fmt.Sprintf("chunk_%03d.wav", chunk.Index))
+	cmd := exec.Command("ffmpeg",
+		"-ss", fmt.Sprintf("%.3f", chunk.Start),
+		"-t", fmt.Sprintf("%.3f", chunk.End-chunk.Start),
+		"-i", audioPath,
+		"-acodec", "pcm_s16le",
+		"-ar", "16000",
+		"-ac", "1",
+		"-y",
+		outputPath,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w, output: %s", err, string(output))
+	}
+
+	chunk.Path = outputPath
+	return nil
+}
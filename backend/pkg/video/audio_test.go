@@ -0,0 +1,86 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanAudioChunks(t *testing.T) {
+	tests := []struct {
+		name          string
+		duration      float64
+		chunkDuration float64
+		overlap       float64
+		silences      []Silence
+		want          []AudioChunk
+	}{
+		{
+			name:          "shorter than a chunk",
+			duration:      8,
+			chunkDuration: 10,
+			overlap:       2,
+			want:          []AudioChunk{{Start: 0, End: 8, CutStart: 0, CutEnd: 8}},
+		},
+		{
+			name:          "chunking disabled",
+			duration:      60,
+			chunkDuration: 0,
+			overlap:       2,
+			want:          []AudioChunk{{Start: 0, End: 60, CutStart: 0, CutEnd: 60}},
+		},
+		{
+			name:          "fixed cuts without silences",
+			duration:      25,
+			chunkDuration: 10,
+			overlap:       2,
+			want: []AudioChunk{
+				{Index: 0, Start: 0, End: 12, CutStart: 0, CutEnd: 10},
+				{Index: 1, Start: 8, End: 22, CutStart: 10, CutEnd: 20},
+				{Index: 2, Start: 18, End: 25, CutStart: 20, CutEnd: 25},
+			},
+		},
+		{
+			name:          "cuts moved back to silences",
+			duration:      25,
+			chunkDuration: 10,
+			overlap:       1,
+			silences:      []Silence{{Start: 8, End: 9}, {Start: 16.5, End: 17.5}},
+			want: []AudioChunk{
+				{Index: 0, Start: 0, End: 9.5, CutStart: 0, CutEnd: 8.5},
+				{Index: 1, Start: 7.5, End: 18, CutStart: 8.5, CutEnd: 17},
+				{Index: 2, Start: 16, End: 25, CutStart: 17, CutEnd: 25},
+			},
+		},
+		{
+			name:          "silences before the last quarter are ignored",
+			duration:      15,
+			chunkDuration: 10,
+			overlap:       2,
+			silences:      []Silence{{Start: 5, End: 6}},
+			want: []AudioChunk{
+				{Index: 0, Start: 0, End: 12, CutStart: 0, CutEnd: 10},
+				{Index: 1, Start: 8, End: 15, CutStart: 10, CutEnd: 15},
+			},
+		},
+		{
+			name:          "latest silence in the window wins",
+			duration:      15,
+			chunkDuration: 10,
+			overlap:       0,
+			silences:      []Silence{{Start: 7.5, End: 8.5}, {Start: 9, End: 9.5}},
+			want: []AudioChunk{
+				{Index: 0, Start: 0, End: 9.25, CutStart: 0, CutEnd: 9.25},
+				{Index: 1, Start: 9.25, End: 15, CutStart: 9.25, CutEnd: 15},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanAudioChunks(tt.duration, tt.chunkDuration, tt.overlap, tt.silences)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("PlanAudioChunks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package video
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Frame sampling modes.
+const (
+	SamplingUniform   = "uniform"   // Count frames evenly spread over the video
+	SamplingScene     = "scene"     // the first frame plus every scene change
+	SamplingKeyframes = "keyframes" // the encoder's keyframes (I-frames)
+)
+
+// Frame is a still image taken from a video.
+type Frame struct {
+	Path      string  `json:"path"`
+	Timestamp float64 `json:"timestamp"` // in seconds
+}
+
+// FrameSampling configures which frames ExtractFrames picks.
+type FrameSampling struct {
+	Mode           string
+	Count          int     // frames for uniform sampling
+	SceneThreshold float64 // 0-1, lower detects more scene changes
+	MaxFrames      int     // upper bound for scene and keyframe sampling
+}
+
+var ptsTimePattern = regexp.MustCompile(`pts_time:\s*([0-9.]+)`)
+
+// ExtractFrames samples frames from the video into outputDir, which is
+// emptied first. duration is only needed for uniform sampling.
+func (p *Processor) ExtractFrames(videoPath, outputDir string, duration float64, sampling FrameSampling) ([]Frame, error) {
+	if err := os.RemoveAll(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to clear frame directory: %w", err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frame directory: %w", err)
+	}
+
+	switch sampling.Mode {
+	case "", SamplingUniform:
+		return p.extractUniformFrames(videoPath, outputDir, duration, sampling.Count)
+	case SamplingScene:
+		filter := fmt.Sprintf("select='eq(n,0)+gt(scene,%.2f)',showinfo", sampling.SceneThreshold)
+		return p.extractFilteredFrames(videoPath, outputDir, nil, filter, sampling.MaxFrames)
+	case SamplingKeyframes:
+		return p.extractFilteredFrames(videoPath, outputDir, []string{"-skip_frame", "nokey"}, "showinfo", sampling.MaxFrames)
+	default:
+		return nil, fmt.Errorf("unknown frame sampling mode: %s", sampling.Mode)
+	}
+}
+
+func (p *Processor) extractUniformFrames(videoPath, outputDir string, duration float64, count int) ([]Frame, error) {
+	if count < 1 {
+		count = 1
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("video duration is required for uniform sampling")
+	}
+
+	frames := make([]Frame, 0, count)
+	for i := 0; i < count; i++ {
+		// Take the middle of each of count equal segments
+		timestamp := duration * (float64(i) + 0.5) / float64(count)
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("frame_%03d.jpg", i+1))
+
+		// -ss before -i seeks on keyframes, which is much faster on long videos
+		cmd := exec.Command("ffmpeg",
+			"-ss", fmt.Sprintf("%.2f", timestamp),
+			"-i", videoPath,
+			"-frames:v", "1",
+			"-q:v", "2",
+			"-y",
+			outputPath,
+		)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg failed: %w, output: %s", err, string(output))
+		}
+
+		frames = append(frames, Frame{Path: outputPath, Timestamp: timestamp})
+	}
+
+	return frames, nil
+}
+
+// extractFilteredFrames writes every frame passing filter and reads their
+// timestamps from the showinfo filter's log output.
+func (p *Processor) extractFilteredFrames(videoPath, outputDir string, inputArgs []string, filter string, maxFrames int) ([]Frame, error) {
+	args := append([]string{}, inputArgs...)
+	args = append(args,
+		"-i", videoPath,
+		"-vf", filter,
+		"-vsync", "vfr",
+		"-q:v", "2",
+		"-y",
+		filepath.Join(outputDir, "frame_%03d.jpg"),
+	)
+
+	output, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w, output: %s", err, string(output))
+	}
+
+	matches := ptsTimePattern.FindAllStringSubmatch(string(output), -1)
+	frames := make([]Frame, 0, len(matches))
+	for i, match := range matches {
+		path := filepath.Join(outputDir, fmt.Sprintf("frame_%03d.jpg", i+1))
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		timestamp, _ := strconv.ParseFloat(match[1], 64)
+		frames = append(frames, Frame{Path: path, Timestamp: timestamp})
+	}
+
+	return thinFrames(frames, maxFrames), nil
+}
+
+// thinFrames keeps at most max frames evenly spread over frames and deletes
+// the image files of the others.
+func thinFrames(frames []Frame, max int) []Frame {
+	if max < 1 || len(frames) <= max {
+		return frames
+	}
+
+	keep := make(map[int]bool, max)
+	for i := 0; i < max; i++ {
+		keep[i*len(frames)/max] = true
+	}
+
+	kept := make([]Frame, 0, max)
+	for i, frame := range frames {
+		if keep[i] {
+			kept = append(kept, frame)
+		} else {
+			os.Remove(frame.Path)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Timestamp < kept[j].Timestamp })
+	return kept
+}
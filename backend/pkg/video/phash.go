@@ -0,0 +1,162 @@
+package video
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os/exec"
+	"sort"
+)
+
+// hashSize is the side of the grayscale thumbnail a frame is reduced to
+// before hashing; the hash keeps the lowest 8x8 DCT frequencies of it.
+const hashSize = 32
+
+// minContrast is the pixel range below which a frame is treated as blank.
+// Black, white and faded frames hash alike in every video, so they are left
+// out of fingerprints.
+const minContrast = 24
+
+var dctCos = func() [8][hashSize]float64 {
+	var table [8][hashSize]float64
+	for u := 0; u < 8; u++ {
+		for x := 0; x < hashSize; x++ {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * hashSize))
+		}
+	}
+	return table
+}()
+
+// PerceptualHash returns the 64-bit DCT hash of a hashSize x hashSize
+// grayscale image. Re-encoding, rescaling, light cropping and small overlays
+// such as watermarks change only a few bits. It reports false for frames
+// without enough contrast to hash.
+func PerceptualHash(gray []byte) (uint64, bool) {
+	if len(gray) != hashSize*hashSize {
+		return 0, false
+	}
+
+	lo, hi := gray[0], gray[0]
+	for _, v := range gray {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	if int(hi)-int(lo) < minContrast {
+		return 0, false
+	}
+
+	// Separable 2D DCT, keeping only the 8x8 lowest frequencies
+	var rows [hashSize][8]float64
+	for y := 0; y < hashSize; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < hashSize; x++ {
+				sum += float64(gray[y*hashSize+x]) * dctCos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coeffs [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < hashSize; y++ {
+				sum += rows[y][u] * dctCos[v][y]
+			}
+			coeffs[v*8+u] = sum
+		}
+	}
+
+	// Compare against the median of the AC coefficients; the DC term is
+	// just the average brightness
+	ac := make([]float64, 63)
+	copy(ac, coeffs[1:])
+	sort.Float64s(ac)
+	median := (ac[31] + ac[32]) / 2
+
+	var hash uint64
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash, true
+}
+
+// HammingDistance counts the bits in which two hashes differ.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FrameHashes samples about count frames evenly across the video in a single
+// ffmpeg pass and returns the perceptual hashes of those with enough
+// contrast, in time order.
+func (p *Processor) FrameHashes(videoPath string, duration float64, count int) ([]uint64, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("video duration is unknown")
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	filter := fmt.Sprintf("fps=%f,scale=%d:%d,format=gray", float64(count)/duration, hashSize, hashSize)
+	cmd := exec.Command("ffmpeg",
+		"-v", "error",
+		"-i", videoPath,
+		"-vf", filter,
+		"-frames:v", fmt.Sprintf("%d", count),
+		"-f", "rawvideo",
+		"-",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w, output: %s", err, stderr.String())
+	}
+
+	hashes := make([]uint64, 0, count)
+	reader := bytes.NewReader(output)
+	frame := make([]byte, hashSize*hashSize)
+	for {
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			break
+		}
+		if hash, ok := PerceptualHash(frame); ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+// Similarity scores how alike two fingerprints are, from 0 to 1: the share
+// of frames of either video that have a frame in the other within
+// maxDistance bits. Order is ignored, so trimmed or re-cut copies still
+// score high. It also returns how many frames matched.
+func Similarity(a, b []uint64, maxDistance int) (float64, int) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+
+	matched := countMatches(a, b, maxDistance) + countMatches(b, a, maxDistance)
+	return float64(matched) / float64(len(a)+len(b)), matched
+}
+
+func countMatches(a, b []uint64, maxDistance int) int {
+	matched := 0
+	for _, x := range a {
+		for _, y := range b {
+			if HammingDistance(x, y) <= maxDistance {
+				matched++
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,76 @@
+package video
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// ErrInvalidVideo is returned by Probe when ffprobe cannot read the file or
+// finds no video stream in it.
+var ErrInvalidVideo = errors.New("file has no readable video stream")
+
+// ProbeInfo is what Probe reads from a video file.
+type ProbeInfo struct {
+	Duration float64 // seconds, 0 when the container does not say
+	Width    int
+	Height   int
+	Codec    string
+}
+
+// Probe checks with ffprobe that a file holds a decodable video stream and
+// returns its duration and the stream's dimensions and codec.
+func (p *Processor) Probe(videoPath string) (*ProbeInfo, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,width,height:format=duration",
+		"-of", "json",
+		videoPath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidVideo, firstLine(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result struct {
+		Streams []struct {
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	if len(result.Streams) == 0 || result.Streams[0].Width == 0 {
+		return nil, ErrInvalidVideo
+	}
+
+	info := &ProbeInfo{
+		Width:  result.Streams[0].Width,
+		Height: result.Streams[0].Height,
+		Codec:  result.Streams[0].CodecName,
+	}
+	info.Duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	return info, nil
+}
+
+func firstLine(b []byte) string {
+	for i, c := range b {
+		if c == '\n' {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
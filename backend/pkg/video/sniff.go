@@ -0,0 +1,48 @@
+package video
+
+import "bytes"
+
+// SniffLength is how many leading bytes SniffContainer needs.
+const SniffLength = 512
+
+var (
+	ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+	asfMagic  = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+)
+
+// ISO base media brands that are not video: audio-only MPEG-4 and HEIF/AVIF images.
+var nonVideoBrands = map[string]bool{
+	"M4A ": true, "M4B ": true, "M4P ": true,
+	"heic": true, "heix": true, "mif1": true, "msf1": true, "avif": true,
+}
+
+// SniffContainer identifies a supported video container from the first
+// bytes of a file (see SniffLength) and returns the usual extension for it,
+// regardless of the file's name.
+func SniffContainer(head []byte) (string, bool) {
+	switch {
+	case len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")):
+		brand := string(head[8:12])
+		switch {
+		case nonVideoBrands[brand]:
+			return "", false
+		case brand == "qt  ":
+			return ".mov", true
+		case brand[:3] == "M4V":
+			return ".m4v", true
+		}
+		return ".mp4", true
+	case bytes.HasPrefix(head, ebmlMagic):
+		if bytes.Contains(head, []byte("webm")) {
+			return ".webm", true
+		}
+		return ".mkv", true
+	case len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("AVI ")):
+		return ".avi", true
+	case bytes.HasPrefix(head, []byte("FLV")):
+		return ".flv", true
+	case bytes.HasPrefix(head, asfMagic):
+		return ".wmv", true
+	}
+	return "", false
+}
@@ -1,7 +1,6 @@
 package whisper
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,76 +8,73 @@ import (
 	"time"
 )
 
+// Client talks to the bundled Flask Whisper service.
 type Client struct {
 	baseURL    string
+	language   string
 	httpClient *http.Client
 }
 
-type TranscribeRequest struct {
-	VideoPath string `json:"video_path"`
-}
-
 type TranscribeResponse struct {
-	Success       bool   `json:"success"`
-	Transcription string `json:"transcription"`
-	Language      string `json:"language"`
-	Error         string `json:"error,omitempty"`
-	Warning       string `json:"warning,omitempty"`
+	Success       bool      `json:"success"`
+	Transcription string    `json:"transcription"`
+	Language      string    `json:"language"`
+	Segments      []Segment `json:"segments"`
+	Error         string    `json:"error,omitempty"`
+	Warning       string    `json:"warning,omitempty"`
 }
 
-func NewClient(baseURL string) *Client {
+func NewClient(baseURL, language string) *Client {
 	return &Client{
-		baseURL: baseURL,
+		baseURL:  baseURL,
+		language: language,
 		httpClient: &http.Client{
 			Timeout: 5 * time.Minute, // Long timeout for transcription
 		},
 	}
 }
 
-// TranscribeAudio sends a video file path to the Whisper service for transcription
-func (c *Client) TranscribeAudio(videoPath string) (string, error) {
-	// Prepare request
-	reqBody := TranscribeRequest{
-		VideoPath: videoPath,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Make HTTP request
+// TranscribeAudio uploads an audio file to the Whisper service for
+// transcription. The file is streamed, so the service does not need access
+// to the worker's filesystem.
+func (c *Client) TranscribeAudio(audioPath string) (*Transcript, error) {
 	url := fmt.Sprintf("%s/transcribe", c.baseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	fields := map[string]string{}
+	if c.language != "" {
+		fields["language"] = c.language
+	}
+	req, err := newUploadRequest(url, "audio", audioPath, fields)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to Whisper service: %w", err)
+		return nil, fmt.Errorf("failed to send request to Whisper service: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Parse response
 	var transcribeResp TranscribeResponse
 	if err := json.Unmarshal(body, &transcribeResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
 	}
 
 	if !transcribeResp.Success {
-		return "", fmt.Errorf("transcription failed: %s", transcribeResp.Error)
+		return nil, fmt.Errorf("transcription failed: %s", transcribeResp.Error)
 	}
 
-	return transcribeResp.Transcription, nil
+	return &Transcript{
+		Text:     transcribeResp.Transcription,
+		Language: transcribeResp.Language,
+		Segments: transcribeResp.Segments,
+	}, nil
 }
 
 // HealthCheck checks if the Whisper service is available
@@ -0,0 +1,41 @@
+package whisper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FakeClient returns a deterministic transcript derived from the audio file
+// without running any model.
+type FakeClient struct{}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+func (c *FakeClient) TranscribeAudio(audioPath string) (*Transcript, error) {
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat audio file: %w", err)
+	}
+
+	// 16kHz mono 16-bit PCM is 32000 bytes per second
+	duration := float64(info.Size()) / 32000
+	name := filepath.Base(audioPath)
+
+	segments := []Segment{
+		{Start: 0, End: duration / 2, Text: fmt.Sprintf("first half of %s", name)},
+		{Start: duration / 2, End: duration, Text: fmt.Sprintf("second half of %s", name)},
+	}
+
+	return &Transcript{
+		Text:     segments[0].Text + " " + segments[1].Text,
+		Language: "en",
+		Segments: segments,
+	}, nil
+}
+
+func (c *FakeClient) HealthCheck() error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package whisper
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIModel = "whisper-1"
+
+// verboseTranscription is the verbose_json response format shared by the
+// OpenAI API and whisper.cpp.
+type verboseTranscription struct {
+	Language string    `json:"language"`
+	Text     string    `json:"text"`
+	Segments []Segment `json:"segments"`
+}
+
+// languageCodes maps the language names returned in verbose_json to the ISO
+// 639-1 codes the bundled service reports.
+var languageCodes = map[string]string{
+	"chinese":   "zh",
+	"english":   "en",
+	"japanese":  "ja",
+	"korean":    "ko",
+	"cantonese": "yue",
+}
+
+func (v *verboseTranscription) transcript() *Transcript {
+	segments := make([]Segment, 0, len(v.Segments))
+	for _, seg := range v.Segments {
+		seg.Text = strings.TrimSpace(seg.Text)
+		if seg.Text != "" {
+			segments = append(segments, seg)
+		}
+	}
+
+	language := strings.ToLower(v.Language)
+	if code, ok := languageCodes[language]; ok {
+		language = code
+	}
+
+	return &Transcript{
+		Text:     strings.TrimSpace(v.Text),
+		Language: language,
+		Segments: segments,
+	}
+}
+
+// OpenAIClient uses an OpenAI-compatible /audio/transcriptions endpoint, such
+// as the OpenAI API itself or a self-hosted faster-whisper server.
+type OpenAIClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	language   string
+	httpClient *http.Client
+}
+
+func NewOpenAIClient(baseURL, apiKey, model, language string) *OpenAIClient {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+
+	return &OpenAIClient{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		language: language,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute, // Long timeout for transcription
+		},
+	}
+}
+
+func (c *OpenAIClient) TranscribeAudio(audioPath string) (*Transcript, error) {
+	fields := map[string]string{
+		"model":                     c.model,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+	}
+	if c.language != "" {
+		fields["language"] = c.language
+	}
+
+	url := fmt.Sprintf("%s/audio/transcriptions", c.baseURL)
+	req, err := newUploadRequest(url, "file", audioPath, fields)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	var result verboseTranscription
+	if err := doJSON(c.httpClient, req, &result); err != nil {
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+
+	return result.transcript(), nil
+}
@@ -0,0 +1,63 @@
+package whisper
+
+import "fmt"
+
+// Supported speech-to-text providers.
+const (
+	ProviderService    = "service"    // the bundled Flask service in whisper-service/
+	ProviderOpenAI     = "openai"     // an OpenAI-compatible /audio/transcriptions endpoint
+	ProviderWhisperCPP = "whispercpp" // the whisper.cpp HTTP server
+	ProviderFake       = "fake"       // deterministic, offline results for tests and local development
+)
+
+// Segment is a stretch of speech with its start and end time in seconds.
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcript is the result of transcribing an audio file.
+type Transcript struct {
+	Text     string
+	Language string
+	Segments []Segment
+}
+
+// Transcriber turns a 16kHz mono WAV file into text.
+type Transcriber interface {
+	TranscribeAudio(audioPath string) (*Transcript, error)
+}
+
+// HealthChecker is implemented by transcribers that can report whether
+// their backend is reachable.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// Config selects and configures a provider.
+type Config struct {
+	Provider   string
+	ServiceURL string // service and whispercpp
+	BaseURL    string // openai; empty means the OpenAI API
+	APIKey     string
+	Model      string
+	Language   string // empty lets the backend detect it
+}
+
+// NewTranscriber creates the transcriber for cfg.Provider. An empty provider
+// means the bundled service.
+func NewTranscriber(cfg Config) (Transcriber, error) {
+	switch cfg.Provider {
+	case "", ProviderService:
+		return NewClient(cfg.ServiceURL, cfg.Language), nil
+	case ProviderOpenAI:
+		return NewOpenAIClient(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Language), nil
+	case ProviderWhisperCPP:
+		return NewWhisperCPPClient(cfg.ServiceURL, cfg.Language), nil
+	case ProviderFake:
+		return NewFakeClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider: %s", cfg.Provider)
+	}
+}
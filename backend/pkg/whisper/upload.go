@@ -0,0 +1,86 @@
+package whisper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// newUploadRequest builds a multipart POST carrying the audio file under
+// fileField plus any extra form fields. The file is streamed rather than
+// buffered; the envelope is built up front so Content-Length is known.
+func newUploadRequest(url, fileField, audioPath string, fields map[string]string) (*http.Request, error) {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat audio file: %w", err)
+	}
+
+	var head, tail bytes.Buffer
+	writer := multipart.NewWriter(&head)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+	}
+	if _, err := writer.CreateFormFile(fileField, filepath.Base(audioPath)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	headLen := head.Len()
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	tail.Write(head.Bytes()[headLen:])
+	head.Truncate(headLen)
+
+	payload := io.MultiReader(&head, file, &tail)
+	req, err := http.NewRequest("POST", url, struct {
+		io.Reader
+		io.Closer
+	}{payload, file})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.ContentLength = int64(head.Len()+tail.Len()) + info.Size()
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+// doJSON sends req and decodes a JSON response body into v. Non-2xx
+// responses are returned as errors including the body.
+func doJSON(httpClient *http.Client, req *http.Request, v interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("transcription request returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package whisper
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WhisperCPPClient talks to the HTTP server shipped with whisper.cpp
+// (examples/server).
+type WhisperCPPClient struct {
+	baseURL    string
+	language   string
+	httpClient *http.Client
+}
+
+func NewWhisperCPPClient(baseURL, language string) *WhisperCPPClient {
+	return &WhisperCPPClient{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		language: language,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute, // Long timeout for transcription
+		},
+	}
+}
+
+func (c *WhisperCPPClient) TranscribeAudio(audioPath string) (*Transcript, error) {
+	fields := map[string]string{
+		"response_format": "verbose_json",
+		"temperature":     "0.0",
+	}
+	if c.language != "" {
+		fields["language"] = c.language
+	}
+
+	url := fmt.Sprintf("%s/inference", c.baseURL)
+	req, err := newUploadRequest(url, "file", audioPath, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var result verboseTranscription
+	if err := doJSON(c.httpClient, req, &result); err != nil {
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+
+	return result.transcript(), nil
+}
+
+// HealthCheck checks if the whisper.cpp server is up and has loaded its model
+func (c *WhisperCPPClient) HealthCheck() error {
+	url := fmt.Sprintf("%s/health", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -1,7 +1,7 @@
 #!/usr/bin/env python3
 """
 Whisper Audio Transcription Microservice
-Provides HTTP API for transcribing audio from video files using Whisper large-v3
+Provides HTTP API for transcribing uploaded audio using Whisper large-v3
 """
 
 from flask import Flask, request, jsonify
@@ -22,6 +22,17 @@ app = Flask(__name__)
 # Global variables for model
 whisper_pipeline = None
 MODEL_PATH = "../whisper-large-v3"
+# Whisper language name, e.g. "chinese" or "english"; "auto" detects it per file
+LANGUAGE = os.environ.get("WHISPER_LANGUAGE", "chinese")
+
+# ISO 639-1 codes reported for the configured language
+LANGUAGE_CODES = {
+    "chinese": "zh",
+    "english": "en",
+    "japanese": "ja",
+    "korean": "ko",
+    "cantonese": "yue",
+}
 
 def initialize_model():
     """Initialize Whisper model on startup"""
@@ -90,6 +101,30 @@ def extract_audio_from_video(video_path, output_audio_path):
         logger.error(f"Error extracting audio: {e}")
         return False
 
+def build_segments(chunks):
+    """Convert pipeline chunks into segments with start/end times in seconds"""
+    segments = []
+    for chunk in chunks:
+        text = chunk.get("text", "").strip()
+        if not text:
+            continue
+        start, end = chunk.get("timestamp", (None, None))
+        start = float(start or 0.0)
+        # The last chunk may have no end time when the audio is cut off
+        end = float(end) if end is not None else start
+        segments.append({"start": round(start, 2), "end": round(end, 2), "text": text})
+    return segments
+
+def detect_language(chunks, language):
+    """Return the language of the transcript as an ISO 639-1 code"""
+    if language != "auto":
+        return LANGUAGE_CODES.get(language, language)
+    for chunk in chunks:
+        language = chunk.get("language")
+        if language:
+            return LANGUAGE_CODES.get(language, language)
+    return "unknown"
+
 @app.route('/health', methods=['GET'])
 def health_check():
     """Health check endpoint"""
@@ -102,9 +137,15 @@ def health_check():
 @app.route('/transcribe', methods=['POST'])
 def transcribe():
     """
-    Transcribe audio from video file
-    
-    Request JSON:
+    Transcribe uploaded audio
+
+    Request (multipart/form-data):
+        audio: 16kHz mono WAV file
+        language: optional ISO 639-1 code or Whisper language name,
+            overriding WHISPER_LANGUAGE
+
+    For compatibility with older clients running on the same host, a JSON
+    body with a local video path is still accepted:
     {
         "video_path": "/path/to/video.mp4"
     }
@@ -113,7 +154,10 @@ def transcribe():
     {
         "success": true,
         "transcription": "transcribed text...",
-        "language": "zh"
+        "language": "zh",
+        "segments": [
+            {"start": 0.0, "end": 4.2, "text": "..."}
+        ]
     }
     """
     if whisper_pipeline is None:
@@ -123,35 +167,42 @@ def transcribe():
         }), 503
     
     try:
-        # Get video path from request
-        data = request.get_json()
-        video_path = data.get('video_path')
+        audio_file = request.files.get('audio')
+        video_path = None
         
-        if not video_path:
-            return jsonify({
-                "success": False,
-                "error": "video_path is required"
-            }), 400
-        
-        if not os.path.exists(video_path):
-            return jsonify({
-                "success": False,
-                "error": f"Video file not found: {video_path}"
-            }), 404
-        
-        logger.info(f"Processing video: {video_path}")
+        if audio_file is None:
+            # Legacy request with a local video path
+            data = request.get_json(silent=True) or {}
+            video_path = data.get('video_path')
+            
+            if not video_path:
+                return jsonify({
+                    "success": False,
+                    "error": "audio file is required"
+                }), 400
+            
+            if not os.path.exists(video_path):
+                return jsonify({
+                    "success": False,
+                    "error": f"Video file not found: {video_path}"
+                }), 404
         
         # Create temporary file for audio
         with tempfile.NamedTemporaryFile(suffix='.wav', delete=False) as temp_audio:
             temp_audio_path = temp_audio.name
         
         try:
-            # Extract audio from video
-            if not extract_audio_from_video(video_path, temp_audio_path):
-                return jsonify({
-                    "success": False,
-                    "error": "Failed to extract audio from video"
-                }), 500
+            if audio_file is not None:
+                logger.info(f"Processing uploaded audio: {audio_file.filename}")
+                audio_file.save(temp_audio_path)
+            else:
+                logger.info(f"Processing video: {video_path}")
+                # Extract audio from video
+                if not extract_audio_from_video(video_path, temp_audio_path):
+                    return jsonify({
+                        "success": False,
+                        "error": "Failed to extract audio from video"
+                    }), 500
             
             # Check if audio file has content
             if os.path.getsize(temp_audio_path) < 1000:
@@ -160,27 +211,33 @@ def transcribe():
                     "success": True,
                     "transcription": "",
                     "language": "unknown",
+                    "segments": [],
                     "warning": "No audio detected in video"
                 })
             
             # Transcribe audio
             logger.info("Starting transcription...")
+            language = request.form.get('language') or LANGUAGE
+            generate_kwargs = {"task": "transcribe"}
+            if language != "auto":
+                generate_kwargs["language"] = language
             result = whisper_pipeline(
                 temp_audio_path,
                 return_timestamps=True,  # Required for videos > 30 seconds
-                generate_kwargs={
-                    "language": "chinese",  # Set to Chinese by default, can be made configurable
-                    "task": "transcribe"
-                }
+                return_language=language == "auto",
+                generate_kwargs=generate_kwargs
             )
             
             transcription = result["text"].strip()
-            logger.info(f"Transcription completed: {transcription[:100]}...")
+            segments = build_segments(result.get("chunks", []))
+            language = detect_language(result.get("chunks", []), language)
+            logger.info(f"Transcription completed ({language}, {len(segments)} segments): {transcription[:100]}...")
             
             return jsonify({
                 "success": True,
                 "transcription": transcription,
-                "language": "zh"
+                "language": language,
+                "segments": segments
             })
             
         finally:
@@ -20,22 +20,23 @@ def test_health():
         print(f"✗ Health check failed: {e}")
         return False
 
-def test_transcribe(video_path):
+def test_transcribe(audio_path):
     """Test transcribe endpoint"""
-    print(f"\nTesting /transcribe endpoint with: {video_path}")
+    print(f"\nTesting /transcribe endpoint with: {audio_path}")
     try:
-        payload = {"video_path": video_path}
-        response = requests.post(
-            "http://localhost:5000/transcribe",
-            json=payload,
-            timeout=300  # 5 minutes
-        )
+        with open(audio_path, "rb") as audio:
+            response = requests.post(
+                "http://localhost:5000/transcribe",
+                files={"audio": audio},
+                timeout=300  # 5 minutes
+            )
         response.raise_for_status()
         data = response.json()
         
         if data.get("success"):
             print(f"✓ Transcription successful!")
             print(f"  Language: {data.get('language', 'unknown')}")
+            print(f"  Segments: {len(data.get('segments', []))}")
             print(f"  Transcription preview: {data.get('transcription', '')[:200]}...")
             return True
         else:
@@ -56,12 +57,12 @@ if __name__ == "__main__":
         print("  python app.py")
         sys.exit(1)
     
-    # Test transcription (if video path provided)
+    # Test transcription (if audio path provided)
     if len(sys.argv) > 1:
-        video_path = sys.argv[1]
-        test_transcribe(video_path)
+        audio_path = sys.argv[1]
+        test_transcribe(audio_path)
     else:
         print("\n✓ All tests passed!")
-        print("\nTo test transcription, provide a video path:")
-        print(f"  python {sys.argv[0]} /path/to/video.mp4")
+        print("\nTo test transcription, provide an audio path:")
+        print(f"  python {sys.argv[0]} /path/to/audio.wav")
 
@@ -2,7 +2,7 @@
 
 import { useState, useRef } from 'react';
 import { useRouter } from 'next/navigation';
-import { videoAPI } from '@/lib/api';
+import { videoAPI, UploadResult } from '@/lib/api';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Progress } from '@/components/ui/progress';
@@ -70,21 +70,37 @@ export default function UploadPage() {
         formData.append('videos', file);
       });
 
-      await videoAPI.upload(formData, (progressEvent) => {
+      const response = await videoAPI.upload(formData, (progressEvent) => {
         const progress = progressEvent.total
           ? Math.round((progressEvent.loaded * 100) / progressEvent.total)
           : 0;
         setUploadProgress(progress);
       });
 
+      const rejected = rejectedFiles(response.data.results);
+      if (rejected) {
+        // Keep only the rejected files selected so they can be fixed and retried
+        const names = new Set((response.data.results as UploadResult[]).filter((r) => !r.accepted).map((r) => r.filename));
+        setFiles(files.filter((file) => names.has(file.name)));
+        setError(`部分文件未上传：${rejected}`);
+        return;
+      }
+
       router.push('/videos');
     } catch (err: any) {
-      setError(err.response?.data?.error || '上传失败');
+      const rejected = rejectedFiles(err.response?.data?.results);
+      setError(rejected ? `上传失败：${rejected}` : err.response?.data?.error || '上传失败');
     } finally {
       setUploading(false);
     }
   };
 
+  const rejectedFiles = (results?: UploadResult[]) =>
+    (results || [])
+      .filter((r) => !r.accepted)
+      .map((r) => `${r.filename}（${r.error}）`)
+      .join('；');
+
   const formatFileSize = (bytes: number) => {
     if (bytes === 0) return '0 字节';
     const k = 1024;
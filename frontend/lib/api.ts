@@ -121,10 +121,23 @@ export interface Video {
   file_size: number;
   duration: number;
   status: 'pending' | 'processing' | 'completed' | 'failed';
+  content_hash?: string;
+  duplicate_of_id?: number; // video whose analysis was reused
+  transcript_language?: string;
+  transcript_segments?: TranscriptSegment[];
   created_at: string;
   updated_at: string;
 }
 
+export interface TranscriptSegment {
+  id: number;
+  video_id: number;
+  position: number;
+  start: number; // seconds
+  end: number;
+  text: string;
+}
+
 export interface Report {
   id: number;
   video_id: number;
@@ -136,6 +149,7 @@ export interface Report {
   risk_level: string;
   detailed_analysis: string;
   recommendations: string;
+  evidence?: string; // JSON array of per-chunk findings for long content
   processing_time: number;
   created_at: string;
 }
@@ -143,7 +157,7 @@ export interface Report {
 export interface Job {
   id: number;
   video_id: number;
-  status: 'pending' | 'processing' | 'completed' | 'failed';
+  status: 'pending' | 'processing' | 'completed' | 'failed' | 'dead';
   retry_count: number;
   error_message?: string;
   created_at: string;
@@ -173,10 +187,67 @@ export const videoAPI = {
     }),
   list: (params?: { page?: number; page_size?: number; status?: string }) =>
     api.get('/api/videos', { params }),
+  import: (urls: string[]) => api.post('/api/videos/import', { urls }),
   get: (id: number) => api.get(`/api/videos/${id}`),
+  similar: (id: number, params?: { min_similarity?: number; limit?: number }) =>
+    api.get(`/api/videos/${id}/similar`, { params }),
   delete: (id: number) => api.delete(`/api/videos/${id}`),
 };
 
+export interface SimilarVideo {
+  video: Video & { user?: User; report?: Report };
+  similarity: number; // 0-1
+  matched_frames: number;
+}
+
+export interface UploadResult {
+  filename: string;
+  accepted: boolean;
+  duplicate?: boolean;
+  video?: Video;
+  code?: 'unsupported_type' | 'too_large' | 'invalid_content' | 'unreadable_video' | 'storage_failed' | 'internal_error';
+  error?: string;
+}
+
+export interface ImportResult {
+  url: string;
+  status: 'imported' | 'duplicate' | 'failed';
+  duplicate?: boolean;
+  video?: Video;
+  error?: string;
+}
+
+export interface Upload {
+  id: number;
+  user_id: number;
+  filename: string;
+  size: number;
+  offset: number;
+  checksum?: string;
+  status: 'uploading' | 'completed';
+  video_id?: number;
+  expires_at: string;
+  created_at: string;
+  updated_at: string;
+}
+
+// Resumable upload APIs; resume a dropped upload from offset()
+export const uploadAPI = {
+  create: (data: { filename: string; size: number; checksum?: string }) => api.post('/api/uploads', data),
+  get: (id: number) => api.get(`/api/uploads/${id}`),
+  offset: async (id: number) => Number((await api.head(`/api/uploads/${id}`)).headers['upload-offset']),
+  patch: (id: number, offset: number, chunk: Blob, onUploadProgress?: (progressEvent: any) => void) =>
+    api.patch(`/api/uploads/${id}`, chunk, {
+      headers: {
+        'Content-Type': 'application/offset+octet-stream',
+        'Upload-Offset': String(offset),
+      },
+      onUploadProgress,
+    }),
+  complete: (id: number) => api.post(`/api/uploads/${id}/complete`),
+  abort: (id: number) => api.delete(`/api/uploads/${id}`),
+};
+
 // Report APIs
 export const reportAPI = {
   getByVideoId: (videoId: number) => api.get(`/api/reports/${videoId}`),
@@ -191,3 +262,285 @@ export const jobAPI = {
     api.get('/api/jobs', { params }),
 };
 
+export interface WatchlistTerm {
+  id: number;
+  watchlist_id: number;
+  term: string;
+  category?: string;
+  match_type: 'contains' | 'word' | 'regex';
+  case_sensitive: boolean;
+}
+
+export interface Watchlist {
+  id: number;
+  user_id: number;
+  tenant?: string;
+  name: string;
+  description?: string;
+  shared: boolean;
+  channels: string; // comma separated: inbox, email, chat, webhook
+  active: boolean;
+  terms?: WatchlistTerm[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RuleCondition {
+  all?: RuleCondition[];
+  any?: RuleCondition[];
+  not?: RuleCondition;
+  field?: string;
+  op?: 'eq' | 'ne' | 'lt' | 'lte' | 'gt' | 'gte' | 'in' | 'contains' | 'matches';
+  value?: string | number | string[];
+}
+
+export interface AlertRule {
+  id: number;
+  user_id: number;
+  name: string;
+  description?: string;
+  condition: string; // JSON encoded RuleCondition
+  severity: 'low' | 'medium' | 'high';
+  channels: string; // comma separated: inbox, email, chat, webhook
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Alert {
+  id: number;
+  user_id: number;
+  video_id: number;
+  report_id: number;
+  source: 'watchlist' | 'rule';
+  watchlist_id?: number;
+  term_id?: number;
+  rule_id?: number;
+  term?: string;
+  category?: string;
+  severity?: 'low' | 'medium' | 'high';
+  location?: 'cover_text' | 'transcript' | 'key_topics';
+  snippet: string;
+  timestamp?: number; // seconds into the video
+  occurrences: number;
+  status: 'open' | 'acknowledged' | 'resolved';
+  acknowledged_at?: string;
+  resolved_at?: string;
+  created_at: string;
+  watchlist?: Watchlist;
+  rule?: AlertRule;
+  video?: Video;
+}
+
+type WatchlistTermInput = Pick<WatchlistTerm, 'term'> &
+  Partial<Pick<WatchlistTerm, 'category' | 'match_type' | 'case_sensitive'>>;
+
+// Watchlist APIs
+export const watchlistAPI = {
+  list: () => api.get('/api/watchlists'),
+  get: (id: number) => api.get(`/api/watchlists/${id}`),
+  create: (data: { name: string; description?: string; shared?: boolean; channels?: string[]; terms?: WatchlistTermInput[] }) =>
+    api.post('/api/watchlists', data),
+  update: (id: number, data: { name?: string; description?: string; shared?: boolean; active?: boolean; channels?: string[]; terms?: WatchlistTermInput[] }) =>
+    api.put(`/api/watchlists/${id}`, data),
+  delete: (id: number) => api.delete(`/api/watchlists/${id}`),
+  addTerm: (id: number, term: WatchlistTermInput) => api.post(`/api/watchlists/${id}/terms`, term),
+  deleteTerm: (id: number, termId: number) => api.delete(`/api/watchlists/${id}/terms/${termId}`),
+};
+
+type AlertRuleInput = {
+  name?: string;
+  description?: string;
+  condition?: RuleCondition;
+  severity?: AlertRule['severity'];
+  channels?: string[];
+  active?: boolean;
+};
+
+type DryRunInput = { since?: string; until?: string; limit?: number };
+
+// Alert rule APIs
+export const ruleAPI = {
+  list: () => api.get('/api/rules'),
+  get: (id: number) => api.get(`/api/rules/${id}`),
+  create: (data: AlertRuleInput & { name: string; condition: RuleCondition }) => api.post('/api/rules', data),
+  update: (id: number, data: AlertRuleInput) => api.put(`/api/rules/${id}`, data),
+  delete: (id: number) => api.delete(`/api/rules/${id}`),
+  dryRun: (data: DryRunInput & { condition: RuleCondition }) => api.post('/api/rules/dry-run', data),
+  dryRunSaved: (id: number, data?: DryRunInput) => api.post(`/api/rules/${id}/dry-run`, data),
+};
+
+// Alert APIs
+export const alertAPI = {
+  list: (params?: { page?: number; page_size?: number; status?: string; source?: string; video_id?: number; watchlist_id?: number; rule_id?: number; location?: string }) =>
+    api.get('/api/alerts', { params }),
+  acknowledge: (id: number) => api.post(`/api/alerts/${id}/acknowledge`),
+  resolve: (id: number) => api.post(`/api/alerts/${id}/resolve`),
+};
+
+export interface Notification {
+  id: number;
+  user_id: number;
+  alert_id?: number;
+  title: string;
+  body: string;
+  link?: string;
+  read_at?: string;
+  created_at: string;
+}
+
+export interface NotificationChannel {
+  id: number;
+  user_id: number;
+  type: 'email' | 'feishu' | 'dingtalk' | 'wecom' | 'slack';
+  name: string;
+  target: string; // email address or webhook URL
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+// Notification APIs
+export const notificationAPI = {
+  list: (params?: { page?: number; page_size?: number; unread?: boolean }) =>
+    api.get('/api/notifications', { params }),
+  markRead: (id: number) => api.post(`/api/notifications/${id}/read`),
+  markAllRead: () => api.post('/api/notifications/read-all'),
+  deliveries: (params?: { page?: number; page_size?: number; status?: string; channel_id?: number }) =>
+    api.get('/api/notifications/deliveries', { params }),
+  listChannels: () => api.get('/api/notification-channels'),
+  createChannel: (data: { type: NotificationChannel['type']; name?: string; target: string; secret?: string }) =>
+    api.post('/api/notification-channels', data),
+  updateChannel: (id: number, data: { name?: string; target?: string; secret?: string; active?: boolean }) =>
+    api.put(`/api/notification-channels/${id}`, data),
+  deleteChannel: (id: number) => api.delete(`/api/notification-channels/${id}`),
+  testChannel: (id: number) => api.post(`/api/notification-channels/${id}/test`),
+};
+
+export interface DigestSchedule {
+  id: number;
+  user_id: number;
+  name: string;
+  period: 'daily' | 'weekly';
+  scope: 'user' | 'tenant';
+  tenant?: string;
+  channels: string; // comma separated: inbox, email, chat, webhook
+  summary: boolean;
+  active: boolean;
+  next_run_at?: string;
+  last_run_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface DigestStats {
+  total: number;
+  by_sentiment: Record<string, number>;
+  by_risk: Record<string, number>;
+  average_score: number;
+  top_topics: { topic: string; count: number; previous_count: number }[];
+  highest_risk: {
+    video_id: number;
+    report_id: number;
+    original_filename: string;
+    risk_level: string;
+    sentiment_score: number;
+    sentiment_label: string;
+  }[];
+  trend: {
+    previous_start: string;
+    previous_end: string;
+    previous_total: number;
+    total_change: number;
+    previous_average_score: number;
+    average_score_change: number;
+    previous_negative: number;
+    negative_change: number;
+    previous_high_risk: number;
+    high_risk_change: number;
+  };
+}
+
+export interface Digest {
+  id: number;
+  user_id: number;
+  schedule_id: number;
+  period: 'daily' | 'weekly';
+  scope: 'user' | 'tenant';
+  period_start: string;
+  period_end: string;
+  report_count: number;
+  stats: string; // JSON DigestStats
+  summary: string;
+  summary_error?: string;
+  created_at: string;
+}
+
+type DigestScheduleInput = {
+  name: string;
+  period: DigestSchedule['period'];
+  scope?: DigestSchedule['scope'];
+  channels?: string[];
+  summary?: boolean;
+};
+
+// Digest APIs
+export const digestAPI = {
+  listSchedules: () => api.get('/api/digest-schedules'),
+  createSchedule: (data: DigestScheduleInput) => api.post('/api/digest-schedules', data),
+  updateSchedule: (id: number, data: Partial<DigestScheduleInput> & { active?: boolean }) =>
+    api.put(`/api/digest-schedules/${id}`, data),
+  deleteSchedule: (id: number) => api.delete(`/api/digest-schedules/${id}`),
+  runSchedule: (id: number) => api.post(`/api/digest-schedules/${id}/run`),
+  list: (params?: { page?: number; page_size?: number; schedule_id?: number; period?: string }) =>
+    api.get('/api/digests', { params }),
+  get: (id: number) => api.get(`/api/digests/${id}`),
+};
+
+export interface JobEvent {
+  id: number;
+  type:
+    | 'job.started'
+    | 'job.stage_started'
+    | 'job.stage_finished'
+    | 'job.completed'
+    | 'job.retrying'
+    | 'job.failed';
+  user_id: number;
+  job_id: number;
+  video_id: number;
+  stage?: string;
+  stage_status?: string;
+  progress: number;
+  attempt?: number;
+  error?: string;
+  report_id?: number;
+  time: string;
+}
+
+const JOB_EVENT_TYPES: JobEvent['type'][] = [
+  'job.started',
+  'job.stage_started',
+  'job.stage_finished',
+  'job.completed',
+  'job.retrying',
+  'job.failed',
+];
+
+// Subscribe to the current user's job events; returns a function that closes the stream
+export const subscribeJobEvents = (
+  onEvent: (event: JobEvent) => void,
+  jobId?: number
+) => {
+  const params = new URLSearchParams();
+  const token = localStorage.getItem('access_token');
+  if (token) params.set('access_token', token);
+  if (jobId) params.set('job_id', String(jobId));
+
+  const source = new EventSource(`${API_URL}/api/events?${params}`, { withCredentials: true });
+  JOB_EVENT_TYPES.forEach((type) => {
+    source.addEventListener(type, (e) => onEvent(JSON.parse((e as MessageEvent).data)));
+  });
+  return () => source.close();
+};
+